@@ -1,10 +1,14 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -21,29 +25,53 @@ func main() {
 	var (
 		httpAddr = fs.String("http-addr", "", "HTTP address of shortsvc")
 		grpcAddr = fs.String("grpc-addr", "", "gRPC address of shortsvc")
-		method   = fs.String("method", "create", "create, lookup")
+		method   = fs.String("method", "create", "create, lookup, find, stat, analytics, list, update, delete, createbatch, lookupbatch, import")
+		ttl      = fs.Duration("ttl", 0, "TTL of created keys, zero never expires")
+		owner    = fs.String("owner", "", "Owner id of created keys, or of listed keys if set")
+		key      = fs.String("key", "", "Custom key to create, generated if empty")
+		since    = fs.Duration("since", 0, "How far back analytics, or the creation of listed keys, go. Zero for all of them")
+		prefix   = fs.String("prefix", "", "Prefix of the values of listed keys")
+		limit    = fs.Int("limit", 0, "Maximum number of listed keys, zero for all of them")
+		cursor   = fs.String("cursor", "", "Cursor to resume a listing from")
+		apiKey   = fs.String("api-key", os.Getenv("SHORTCLI_API_KEY"), "API key to authenticate with (default $SHORTCLI_API_KEY)")
 	)
-	fs.Usage = usageFor(fs, os.Args[0]+" [flags] <arg>")
+	fs.Usage = usageFor(fs, os.Args[0]+" [flags] <arg> [<arg>...]\n  "+os.Args[0]+" -grpc-addr=<addr> -method=import < values\n  "+os.Args[0]+" -method=list [-owner=<id>] [-prefix=<v>] [-since=<d>]")
 	fs.Parse(os.Args[1:])
-	if len(fs.Args()) != 1 {
+	var validArgs bool
+	switch nargs := len(fs.Args()); *method {
+	case "update":
+		validArgs = nargs == 2
+	case "createbatch", "lookupbatch":
+		validArgs = nargs > 0
+	case "import", "list":
+		validArgs = nargs == 0
+	default:
+		validArgs = nargs == 1
+	}
+	if !validArgs {
 		fs.Usage()
 		os.Exit(1)
 	}
 
 	var (
-		svc shortservice.Service
-		err error
+		svc     shortservice.Service
+		conn    *grpc.ClientConn
+		options []shorttransport.ClientOption
+		err     error
 	)
+	if *apiKey != "" {
+		options = append(options, shorttransport.WithCredentials(*apiKey))
+	}
 	if *httpAddr != "" {
-		svc, err = shorttransport.NewHTTPClient(*httpAddr, log.NewNopLogger())
+		svc, err = shorttransport.NewHTTPClient(*httpAddr, log.NewNopLogger(), options...)
 	} else if *grpcAddr != "" {
-		conn, err := grpc.Dial(*grpcAddr, grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+		conn, err = grpc.Dial(*grpcAddr, grpc.WithInsecure(), grpc.WithTimeout(time.Second))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v", err)
 			os.Exit(1)
 		}
 		defer conn.Close()
-		svc = shorttransport.NewGRPCClient(conn, log.NewNopLogger())
+		svc = shorttransport.NewGRPCClient(conn, log.NewNopLogger(), options...)
 	} else {
 		fmt.Fprintf(os.Stderr, "error: no remote address specified\n")
 		os.Exit(1)
@@ -56,7 +84,11 @@ func main() {
 	switch *method {
 	case "create":
 		value := fs.Args()[0]
-		k, err := svc.Create(context.Background(), value)
+		k, err := svc.Create(context.Background(), value,
+			shortservice.WithTTL(*ttl),
+			shortservice.WithOwner(*owner),
+			shortservice.WithKey(*key),
+		)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
@@ -72,12 +104,225 @@ func main() {
 		}
 		fmt.Fprintf(os.Stdout, "%s\n", v)
 
+	case "find":
+		value := fs.Args()[0]
+		k, err := svc.Find(context.Background(), value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stdout, "%s\n", k)
+
+	case "stat":
+		k := fs.Args()[0]
+		m, err := svc.Stat(context.Background(), k)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+		fmt.Fprintf(w, "owner\t%s\n", m.Owner)
+		fmt.Fprintf(w, "created\t%s\n", m.CreatedAt)
+		fmt.Fprintf(w, "expires\t%s\n", m.ExpiresAt)
+		fmt.Fprintf(w, "accessed\t%s\n", m.AccessedAt)
+		fmt.Fprintf(w, "lookups\t%d\n", m.Lookups)
+		if m.Original != "" {
+			fmt.Fprintf(w, "original\t%s\n", m.Original)
+		}
+		w.Flush()
+
+	case "analytics":
+		k := fs.Args()[0]
+		var from time.Time
+		if *since > 0 {
+			from = time.Now().Add(-*since)
+		}
+		a, err := svc.Analytics(context.Background(), k, from, time.Time{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+		fmt.Fprintf(w, "visitors\t%d\n", a.Visitors)
+		for _, b := range a.Daily {
+			fmt.Fprintf(w, "day\t%s\t%d\n", b.Start.Format("2006-01-02"), b.Lookups)
+		}
+		for _, b := range a.Hourly {
+			fmt.Fprintf(w, "hour\t%s\t%d\n", b.Start.Format("2006-01-02 15:04"), b.Lookups)
+		}
+		printCounts(w, "referrer", a.Referrers)
+		printCounts(w, "agent", a.Agents)
+		w.Flush()
+
+	case "list":
+		filter := shortservice.Filter{Owner: *owner, Prefix: *prefix}
+		if *since > 0 {
+			filter.From = time.Now().Add(-*since)
+		}
+		next, err := listEntries(context.Background(), svc, os.Stdout, *cursor, *limit, filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if next != "" {
+			fmt.Fprintf(os.Stderr, "more entries after -cursor=%s\n", next)
+		}
+
+	case "update":
+		k, v := fs.Args()[0], fs.Args()[1]
+		if err := svc.Update(context.Background(), k, v); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "delete":
+		k := fs.Args()[0]
+		if err := svc.Delete(context.Background(), k); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "createbatch":
+		results, err := svc.CreateBatch(context.Background(), fs.Args(),
+			shortservice.WithTTL(*ttl),
+			shortservice.WithOwner(*owner),
+		)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		printResults(results, func(r shortservice.Result) (string, string) { return r.V, r.K })
+
+	case "lookupbatch":
+		results, err := svc.LookupBatch(context.Background(), fs.Args())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		printResults(results, func(r shortservice.Result) (string, string) { return r.K, r.V })
+
+	case "import":
+		if conn == nil {
+			fmt.Fprintf(os.Stderr, "error: import requires -grpc-addr\n")
+			os.Exit(1)
+		}
+		ctx := context.Background()
+		if *apiKey != "" {
+			ctx = shorttransport.AuthContext(ctx, *apiKey)
+		}
+		if err := importValues(ctx, conn, os.Stdin, os.Stdout,
+			shortservice.WithTTL(*ttl),
+			shortservice.WithOwner(*owner),
+		); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+
 	default:
 		fmt.Fprintf(os.Stderr, "error: invalid method %q\n", *method)
 		os.Exit(1)
 	}
 }
 
+// printResults prints a line per batch result, pairing the input of each
+// item with its output or error.
+func printResults(results []shortservice.Result, pair func(shortservice.Result) (in, out string)) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	for _, r := range results {
+		in, out := pair(r)
+		if r.Err != nil {
+			out = "error: " + r.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\n", in, out)
+	}
+	w.Flush()
+}
+
+// printCounts prints a line per count in m, most common first.
+func printCounts(w io.Writer, label string, m map[string]uint64) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if m[names[i]] != m[names[j]] {
+			return m[names[i]] > m[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", label, name, m[name])
+	}
+}
+
+// listEntries writes a line per entry selected by filter to w, starting
+// after cursor, a page at a time until limit entries are written or there
+// are no more. It returns the cursor resuming after the last entry written,
+// empty if there are no more.
+func listEntries(ctx context.Context, svc shortservice.Service, w io.Writer, cursor string, limit int, filter shortservice.Filter) (string, error) {
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	defer tw.Flush()
+	for n := 0; limit == 0 || n < limit; {
+		size := shortservice.MaxListLimit
+		if limit > 0 && limit-n < size {
+			size = limit - n
+		}
+		page, err := svc.List(ctx, cursor, size, filter)
+		if err != nil {
+			return cursor, err
+		}
+		for _, item := range page.Items {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", item.K, item.V, item.Owner, item.CreatedAt.Format(time.RFC3339))
+		}
+		n += len(page.Items)
+		if cursor = page.Cursor; cursor == "" {
+			break
+		}
+	}
+	return cursor, nil
+}
+
+// importValues streams every non-empty line of r to the server and writes
+// each value and its key, or error, to w as results arrive.
+func importValues(ctx context.Context, conn *grpc.ClientConn, r io.Reader, w io.Writer, opts ...shortservice.CreateOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	values := make(chan string)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(values)
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			v := strings.TrimSpace(s.Text())
+			if v == "" {
+				continue
+			}
+			select {
+			case values <- v:
+			case <-ctx.Done():
+				scanErr <- nil
+				return
+			}
+		}
+		scanErr <- s.Err()
+	}()
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	err := shorttransport.ImportGRPC(ctx, conn, values, func(r shortservice.Result) {
+		if r.Err != nil {
+			fmt.Fprintf(bw, "%s\terror: %v\n", r.V, r.Err)
+			return
+		}
+		fmt.Fprintf(bw, "%s\t%s\n", r.V, r.K)
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	return <-scanErr
+}
+
 func usageFor(fs *flag.FlagSet, short string) func() {
 	return func() {
 		fmt.Fprintf(os.Stderr, "USAGE\n")
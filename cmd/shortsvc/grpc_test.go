@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/discard"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/sgarcez/short/pb"
+	"github.com/sgarcez/short/pkg/shortendpoint"
+	"github.com/sgarcez/short/pkg/shortservice"
+	"github.com/sgarcez/short/pkg/shorttransport"
+)
+
+func TestGRPCErrors(t *testing.T) {
+	conn, stop := serveGRPC(t)
+	defer stop()
+	ctx := context.Background()
+
+	_, err := pb.NewShortenClient(conn).Lookup(ctx, &pb.LookupRequest{K: "gnzLDu"})
+	if want, have := codes.NotFound, status.Code(err); want != have {
+		t.Errorf("Lookup: want code %v, have %v (%v)", want, have, err)
+	}
+
+	client := shorttransport.NewGRPCClient(conn, log.NewNopLogger())
+	if _, err := client.Lookup(ctx, "gnzLDu"); err != shortservice.ErrKeyNotFound {
+		t.Errorf("client Lookup: want %v, have %v", shortservice.ErrKeyNotFound, err)
+	}
+	if _, err := client.Create(ctx, "http://a.com", shortservice.WithKey("abc")); err != shortservice.ErrInvalidKey {
+		t.Errorf("client Create: want %v, have %v", shortservice.ErrInvalidKey, err)
+	}
+	results, err := client.LookupBatch(ctx, []string{"gnzLDu"})
+	if err != nil || results[0].Err != shortservice.ErrKeyNotFound {
+		t.Errorf("client LookupBatch: want %v, have %v (%v)", shortservice.ErrKeyNotFound, results, err)
+	}
+	if _, err := client.Find(ctx, "http://a.com"); err != shortservice.ErrKeyNotFound {
+		t.Errorf("client Find: want %v, have %v", shortservice.ErrKeyNotFound, err)
+	}
+}
+
+func TestGRPCImport(t *testing.T) {
+	conn, stop := serveGRPC(t)
+	defer stop()
+
+	values := make(chan string)
+	go func() {
+		defer close(values)
+		for i := 0; i < 250; i++ {
+			values <- fmt.Sprintf("http://a.com/%d", i)
+		}
+	}()
+
+	var results []shortservice.Result
+	err := shorttransport.ImportGRPC(context.Background(), conn, values, func(r shortservice.Result) {
+		results = append(results, r)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 250, len(results); want != have {
+		t.Fatalf("want %d results, have %d", want, have)
+	}
+	for i, r := range results {
+		if want := fmt.Sprintf("http://a.com/%d", i); r.V != want || r.K == "" || r.Err != nil {
+			t.Fatalf("result %d: want key for %q, have %q for %q (%v)", i, want, r.K, r.V, r.Err)
+		}
+	}
+}
+
+func TestGRPCClientRateLimit(t *testing.T) {
+	limiter := shortendpoint.NewClientLimiter(shortendpoint.ClientLimits{Default: shortendpoint.Tier{Rate: 0.5, Burst: 1}, Size: 10})
+	conn, stop := serveGRPC(t, shortendpoint.WithClientLimiter(limiter))
+	defer stop()
+	client := pb.NewShortenClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), shorttransport.ClientIDHeader, "a")
+
+	client.Lookup(ctx, &pb.LookupRequest{K: "gnzLDu"})
+	_, err := client.Lookup(ctx, &pb.LookupRequest{K: "gnzLDu"})
+	st := status.Convert(err)
+	if want, have := codes.ResourceExhausted, st.Code(); want != have {
+		t.Fatalf("Lookup: want code %v, have %v (%v)", want, have, err)
+	}
+	var retryInfo *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			retryInfo = ri
+		}
+	}
+	if retryInfo == nil || retryInfo.RetryDelay.Seconds < 1 {
+		t.Errorf("Lookup: want a retry delay, have %v", st.Details())
+	}
+
+	// The metadata of an untrusted caller is ignored, so b shares the
+	// bucket of its address with a.
+	other := metadata.AppendToOutgoingContext(context.Background(), shorttransport.ClientIDHeader, "b")
+	if _, err := client.Lookup(other, &pb.LookupRequest{K: "gnzLDu"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Lookup as b: want code %v, have %v", codes.ResourceExhausted, err)
+	}
+}
+
+func TestGRPCAuth(t *testing.T) {
+	keys := shortendpoint.NewKeys(map[string][]shortendpoint.Scope{"writer": {shortendpoint.ScopeCreate}})
+	conn, stop := serveGRPC(t, shortendpoint.WithAuth(keys))
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := pb.NewShortenClient(conn).Create(ctx, &pb.CreateRequest{V: "12345"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Create: want code %v, have %v", codes.Unauthenticated, err)
+	}
+	writer := shorttransport.NewGRPCClient(conn, log.NewNopLogger(), shorttransport.WithCredentials("writer"))
+	if k, err := writer.Create(ctx, "12345"); k != "gnzLDu" || err != nil {
+		t.Errorf("writer Create: want gnzLDu, have %q (%v)", k, err)
+	}
+	if _, err := writer.Lookup(ctx, "gnzLDu"); err != shortendpoint.ErrForbidden {
+		t.Errorf("writer Lookup: want %v, have %v", shortendpoint.ErrForbidden, err)
+	}
+
+	values := make(chan string, 1)
+	values <- "http://a.com"
+	close(values)
+	var results []shortservice.Result
+	err := shorttransport.ImportGRPC(shorttransport.AuthContext(ctx, "writer"), conn, values, func(r shortservice.Result) {
+		results = append(results, r)
+	})
+	if err != nil || len(results) != 1 || results[0].Err != nil {
+		t.Errorf("Import: want a result, have %v (%v)", results, err)
+	}
+	values = make(chan string, 1)
+	values <- "http://b.com"
+	close(values)
+	if err := shorttransport.ImportGRPC(ctx, conn, values, func(shortservice.Result) {}); err != shortendpoint.ErrUnauthenticated {
+		t.Errorf("anonymous Import: want %v, have %v", shortendpoint.ErrUnauthenticated, err)
+	}
+}
+
+func TestGRPCAnalytics(t *testing.T) {
+	ctx := context.Background()
+	conn, stop := serveGRPC(t)
+	client := shorttransport.NewGRPCClient(conn, log.NewNopLogger())
+	if _, err := client.Analytics(ctx, "gnzLDu", time.Time{}, time.Time{}); err != shortservice.ErrAnalyticsDisabled {
+		t.Errorf("Analytics: want %v, have %v", shortservice.ErrAnalyticsDisabled, err)
+	}
+	stop()
+
+	collector := shortservice.NewCollector(time.Hour, 10, 0, discard.NewCounter())
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(), shortservice.WithAnalytics(collector))
+	conn, stop = serveGRPCService(t, svc)
+	defer stop()
+	client = shorttransport.NewGRPCClient(conn, log.NewNopLogger())
+
+	k, err := client.Create(ctx, "http://a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Lookup(ctx, k); err != nil {
+		t.Fatal(err)
+	}
+	// A caller looking keys up on behalf of a visitor forwards the visit.
+	visit := shortservice.Visit{Visitor: "1.1.1.1", Referrer: "https://news.example.com/", UserAgent: "Mozilla/5.0 (X11; Linux x86_64)"}
+	if _, err := client.Lookup(shortservice.WithVisit(ctx, visit), k); err != nil {
+		t.Fatal(err)
+	}
+	collector.Close()
+
+	a, err := client.Analytics(ctx, k, time.Now().Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Hourly) != 1 || a.Hourly[0].Lookups != 2 || a.Visitors != 2 {
+		t.Errorf("Analytics: want 2 lookups by 2 visitors, have %v by %d", a.Hourly, a.Visitors)
+	}
+	if want := map[string]uint64{"news.example.com": 1, shortservice.ReferrerDirect: 1}; !reflect.DeepEqual(want, a.Referrers) {
+		t.Errorf("Analytics: want referrers %v, have %v", want, a.Referrers)
+	}
+	if want := map[string]uint64{shortservice.AgentTool: 1, shortservice.AgentBrowser: 1}; !reflect.DeepEqual(want, a.Agents) {
+		t.Errorf("Analytics: want agents %v, have %v", want, a.Agents)
+	}
+}
+
+// serveGRPC starts a gRPC server backed by an in memory service and returns
+// a connection to it.
+func serveGRPC(t *testing.T, opts ...shortendpoint.Option) (*grpc.ClientConn, func()) {
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	return serveGRPCService(t, svc, opts...)
+}
+
+// serveGRPCService starts a gRPC server backed by svc and returns a
+// connection to it.
+func serveGRPCService(t *testing.T, svc shortservice.Service, opts ...shortendpoint.Option) (*grpc.ClientConn, func()) {
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram(), opts...)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterShortenServer(srv, shorttransport.NewGRPCServer(eps, log.NewNopLogger()))
+	go srv.Serve(ln)
+
+	conn, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		srv.Stop()
+		t.Fatal(err)
+	}
+	return conn, func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestGRPCList(t *testing.T) {
+	conn, stop := serveGRPC(t)
+	defer stop()
+	ctx := context.Background()
+
+	client := shorttransport.NewGRPCClient(conn, log.NewNopLogger())
+	values := make([]string, 250)
+	for i := range values {
+		values[i] = fmt.Sprintf("http://a.com/%d", i)
+	}
+	if _, err := client.CreateBatch(ctx, values, shortservice.WithOwner("alice")); err != nil {
+		t.Fatal(err)
+	}
+	k, err := client.Create(ctx, "http://b.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, err := client.Find(ctx, "http://b.com"); have != k || err != nil {
+		t.Errorf("client Find: want %q, have %q (%v)", k, have, err)
+	}
+
+	// list reads a whole stream, returning the size and cursor of each page.
+	list := func(req *pb.ListRequest) (sizes []int, cursors []string) {
+		stream, err := pb.NewShortenClient(conn).List(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			reply, err := stream.Recv()
+			if err == io.EOF {
+				return sizes, cursors
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sizes = append(sizes, len(reply.Items))
+			cursors = append(cursors, reply.Cursor)
+		}
+	}
+
+	sizes, cursors := list(&pb.ListRequest{Owner: "alice"})
+	if want := []int{100, 100, 50}; !reflect.DeepEqual(want, sizes) || cursors[2] != "" {
+		t.Errorf("List: want pages %v, have %v ending at %q", want, sizes, cursors[len(cursors)-1])
+	}
+	sizes, cursors = list(&pb.ListRequest{Owner: "alice", Limit: 150})
+	if want := []int{100, 50}; !reflect.DeepEqual(want, sizes) || cursors[1] == "" {
+		t.Fatalf("List with limit: want pages %v and a cursor, have %v ending at %q", want, sizes, cursors[len(cursors)-1])
+	}
+	if sizes, _ = list(&pb.ListRequest{Owner: "alice", Cursor: cursors[1]}); !reflect.DeepEqual([]int{100}, sizes) {
+		t.Errorf("List after cursor: want pages [100], have %v", sizes)
+	}
+
+	page, err := client.List(ctx, "", 0, shortservice.Filter{Prefix: "http://b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].V != "http://b.com" || page.Items[0].CreatedAt.IsZero() || page.Cursor != "" {
+		t.Errorf("client List: want http://b.com, have %+v", page)
+	}
+	page, err = client.List(ctx, "", 120, shortservice.Filter{Owner: "alice"})
+	if err != nil || len(page.Items) != 120 || page.Cursor == "" {
+		t.Errorf("client List: want 120 items and a cursor, have %d and %q (%v)", len(page.Items), page.Cursor, err)
+	}
+	if _, err := client.List(ctx, "not a cursor!", 0, shortservice.Filter{}); err != shortservice.ErrInvalidCursor {
+		t.Errorf("client List: want %v, have %v", shortservice.ErrInvalidCursor, err)
+	}
+}
+
+func TestGRPCCanonical(t *testing.T) {
+	ctx := context.Background()
+	canonical := shortservice.NewCanonicalizer(shortservice.CanonicalAll)
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(), shortservice.WithCanonicalizer(canonical))
+	conn, stop := serveGRPCService(t, svc)
+	defer stop()
+	client := shorttransport.NewGRPCClient(conn, log.NewNopLogger())
+
+	const original = "HTTP://Example.com:80/?utm_source=mail"
+	k, err := client.Create(ctx, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err := client.Stat(ctx, k); m.Original != original || err != nil {
+		t.Errorf("client Stat: want original %q, have %q (%v)", original, m.Original, err)
+	}
+	page, err := client.List(ctx, "", 0, shortservice.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].V != "http://example.com/" || page.Items[0].Original != original {
+		t.Errorf("client List: want http://example.com/ given as %q, have %+v", original, page.Items)
+	}
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics/discard"
@@ -27,6 +31,14 @@ func TestHTTP(t *testing.T) {
 	}{
 		{"POST", srv.URL + "/api", `{"v":"12345"}`, `{"k":"gnzLDu"}`},
 		{"GET", srv.URL + "/api/gnzLDu", ``, `{"v":"12345"}`},
+		{"PUT", srv.URL + "/api/gnzLDu", `{"v":"54321"}`, `{}`},
+		{"GET", srv.URL + "/api/gnzLDu", ``, `{"v":"54321"}`},
+		{"DELETE", srv.URL + "/api/gnzLDu", ``, `{}`},
+		{"GET", srv.URL + "/api/gnzLDu", ``, `{"error":"key not found"}`},
+		{"POST", srv.URL + "/api/batch", `{"vs":["12345"]}`, `{"results":[{"k":"gnzLDu","v":"12345"}]}`},
+		{"GET", srv.URL + "/api/batch?k=gnzLDu&k=nope", ``, `{"results":[{"k":"gnzLDu","v":"12345"},{"k":"nope","error":"key not found"}]}`},
+		{"GET", srv.URL + "/api?v=12345", ``, `{"k":"gnzLDu"}`},
+		{"GET", srv.URL + "/api?v=54321", ``, `{"error":"key not found"}`},
 	} {
 		req, _ := http.NewRequest(testcase.method, testcase.url, strings.NewReader(testcase.body))
 		resp, _ := http.DefaultClient.Do(req)
@@ -36,3 +48,392 @@ func TestHTTP(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPStat(t *testing.T) {
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram())
+	srv := httptest.NewServer(shorttransport.NewHTTPHandler(eps, log.NewNopLogger()))
+	defer srv.Close()
+
+	get := func(url string) (int, string) {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	before := time.Now()
+	resp, err := http.Post(srv.URL+"/api", "application/json", strings.NewReader(`{"v":"12345","owner":"alice"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	get(srv.URL + "/api/gnzLDu")
+
+	code, body := get(srv.URL + "/api/gnzLDu/stat")
+	var m shortservice.Metadata
+	if err := json.Unmarshal([]byte(body), &m); code != http.StatusOK || err != nil {
+		t.Fatalf("GET stat: want %d, have %d %q (%v)", http.StatusOK, code, body, err)
+	}
+	if m.Owner != "alice" || m.Lookups != 1 || m.CreatedAt.Before(before.Truncate(time.Second)) || m.AccessedAt.Before(m.CreatedAt) {
+		t.Errorf("GET stat: want owner alice and 1 lookup, have %+v", m)
+	}
+	if code, body := get(srv.URL + "/api/nope/stat"); code != http.StatusNotFound || body != `{"error":"key not found"}` {
+		t.Errorf("GET stat of a missing key: want %d, have %d %q", http.StatusNotFound, code, body)
+	}
+}
+
+func TestHTTPClientErrors(t *testing.T) {
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram())
+	srv := httptest.NewServer(shorttransport.NewHTTPHandler(eps, log.NewNopLogger()))
+	defer srv.Close()
+
+	client, err := shorttransport.NewHTTPClient(srv.URL, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if _, err := client.Lookup(ctx, "gnzLDu"); err != shortservice.ErrKeyNotFound {
+		t.Errorf("Lookup: want %v, have %v", shortservice.ErrKeyNotFound, err)
+	}
+	if _, err := client.Create(ctx, "http://a.com", shortservice.WithKey("abc")); err != shortservice.ErrInvalidKey {
+		t.Errorf("Create: want %v, have %v", shortservice.ErrInvalidKey, err)
+	}
+	if err := client.Delete(ctx, "gnzLDu"); err != shortservice.ErrKeyNotFound {
+		t.Errorf("Delete: want %v, have %v", shortservice.ErrKeyNotFound, err)
+	}
+	results, err := client.LookupBatch(ctx, []string{"gnzLDu"})
+	if err != nil || results[0].Err != shortservice.ErrKeyNotFound {
+		t.Errorf("LookupBatch: want %v, have %v (%v)", shortservice.ErrKeyNotFound, results, err)
+	}
+}
+
+func TestHTTPClientRateLimit(t *testing.T) {
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	limiter := shortendpoint.NewClientLimiter(shortendpoint.ClientLimits{Default: shortendpoint.Tier{Rate: 0.5, Burst: 1}, Size: 10})
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram(), shortendpoint.WithClientLimiter(limiter))
+	proxies, err := shorttransport.ParseTrustedProxies("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(shorttransport.NewHTTPHandler(eps, log.NewNopLogger(), shorttransport.WithTrustedProxies(proxies...)))
+	defer srv.Close()
+
+	lookup := func(client string) *http.Response {
+		req, _ := http.NewRequest("GET", srv.URL+"/api/gnzLDu", nil)
+		if client != "" {
+			req.Header.Set(shorttransport.ClientIDHeader, client)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := lookup("a"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("a: want %d, have %d", http.StatusNotFound, resp.StatusCode)
+	}
+	resp := lookup("a")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("a: want %d, have %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+	if want, have := "2", resp.Header.Get("Retry-After"); want != have {
+		t.Errorf("a: want Retry-After %q, have %q", want, have)
+	}
+	if resp := lookup("b"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("b: want %d, have %d", http.StatusNotFound, resp.StatusCode)
+	}
+	// Without the header the caller is identified by its address.
+	lookup("")
+	if resp := lookup(""); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("anonymous: want %d, have %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+}
+
+func TestHTTPClientIdentity(t *testing.T) {
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	limiter := shortendpoint.NewClientLimiter(shortendpoint.ClientLimits{Default: shortendpoint.Tier{Rate: 0.5, Burst: 1}, Size: 10})
+	keys := shortendpoint.NewKeys(map[string][]shortendpoint.Scope{
+		"alice": {shortendpoint.ScopeLookup},
+		"bob":   {shortendpoint.ScopeLookup},
+	})
+
+	lookup := func(srv *httptest.Server, client, key string) int {
+		req, _ := http.NewRequest("GET", srv.URL+"/api/gnzLDu", nil)
+		req.Header.Set(shorttransport.ClientIDHeader, client)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The header of an untrusted caller is ignored, so fresh ids don't
+	// dodge the limit of its address.
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram(), shortendpoint.WithClientLimiter(limiter))
+	srv := httptest.NewServer(shorttransport.NewHTTPHandler(eps, log.NewNopLogger()))
+	lookup(srv, "a", "")
+	if code := lookup(srv, "b", ""); code != http.StatusTooManyRequests {
+		t.Errorf("untrusted b: want %d, have %d", http.StatusTooManyRequests, code)
+	}
+	srv.Close()
+
+	// Authenticated callers are identified by their key, whatever the
+	// header says.
+	eps = shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram(), shortendpoint.WithClientLimiter(limiter), shortendpoint.WithAuth(keys))
+	srv = httptest.NewServer(shorttransport.NewHTTPHandler(eps, log.NewNopLogger()))
+	defer srv.Close()
+	if code := lookup(srv, "a", "alice"); code != http.StatusNotFound {
+		t.Errorf("alice: want %d, have %d", http.StatusNotFound, code)
+	}
+	if code := lookup(srv, "b", "alice"); code != http.StatusTooManyRequests {
+		t.Errorf("alice as b: want %d, have %d", http.StatusTooManyRequests, code)
+	}
+	if code := lookup(srv, "a", "bob"); code != http.StatusNotFound {
+		t.Errorf("bob: want %d, have %d", http.StatusNotFound, code)
+	}
+}
+
+func TestHTTPAuth(t *testing.T) {
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	keys := shortendpoint.NewKeys(map[string][]shortendpoint.Scope{
+		"reader": {shortendpoint.ScopeLookup},
+		"writer": {shortendpoint.ScopeCreate, shortendpoint.ScopeLookup},
+	})
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram(), shortendpoint.WithAuth(keys))
+	srv := httptest.NewServer(shorttransport.NewHTTPHandler(eps, log.NewNopLogger()))
+	defer srv.Close()
+
+	for _, testcase := range []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"nope", http.StatusUnauthorized},
+		{"reader", http.StatusForbidden},
+		{"writer", http.StatusOK},
+	} {
+		req, _ := http.NewRequest("POST", srv.URL+"/api", strings.NewReader(`{"v":"12345"}`))
+		if testcase.token != "" {
+			req.Header.Set("Authorization", "Bearer "+testcase.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != testcase.want {
+			t.Errorf("Create with %q: want %d, have %d", testcase.token, testcase.want, resp.StatusCode)
+		}
+	}
+
+	ctx := context.Background()
+	anonymous, err := shorttransport.NewHTTPClient(srv.URL, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous.Lookup(ctx, "gnzLDu"); err != shortendpoint.ErrUnauthenticated {
+		t.Errorf("anonymous Lookup: want %v, have %v", shortendpoint.ErrUnauthenticated, err)
+	}
+	reader, err := shorttransport.NewHTTPClient(srv.URL, log.NewNopLogger(), shorttransport.WithCredentials("reader"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := reader.Lookup(ctx, "gnzLDu"); v != "12345" || err != nil {
+		t.Errorf("reader Lookup: want 12345, have %q (%v)", v, err)
+	}
+	if _, err := reader.Create(ctx, "http://a.com"); err != shortendpoint.ErrForbidden {
+		t.Errorf("reader Create: want %v, have %v", shortendpoint.ErrForbidden, err)
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram())
+	handler, err := shorttransport.NewRedirectHandler(eps, log.NewNopLogger(), shorttransport.WithRedirectCode(http.StatusMovedPermanently))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	ctx := context.Background()
+	k, err := svc.Create(ctx, "http://a.com/x?y=z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, "12345"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, "http://b.com", shortservice.WithKey("gone"), shortservice.WithTTL(time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for _, testcase := range []struct {
+		method, key  string
+		code         int
+		location     string
+		cacheControl string
+	}{
+		{"GET", k, http.StatusMovedPermanently, "http://a.com/x?y=z", "no-store"},
+		{"HEAD", k, http.StatusMovedPermanently, "http://a.com/x?y=z", "no-store"},
+		{"GET", "gnzLDu", http.StatusNotFound, "", "no-store"},
+		{"GET", "nope", http.StatusNotFound, "", "no-store"},
+		{"GET", "gone", http.StatusGone, "", "no-store"},
+		{"POST", k, http.StatusMethodNotAllowed, "", ""},
+	} {
+		req, _ := http.NewRequest(testcase.method, srv.URL+"/"+testcase.key, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != testcase.code {
+			t.Errorf("%s /%s: want %d, have %d", testcase.method, testcase.key, testcase.code, resp.StatusCode)
+		}
+		if have := resp.Header.Get("Location"); have != testcase.location {
+			t.Errorf("%s /%s: want Location %q, have %q", testcase.method, testcase.key, testcase.location, have)
+		}
+		if have := resp.Header.Get("Cache-Control"); have != testcase.cacheControl {
+			t.Errorf("%s /%s: want Cache-Control %q, have %q", testcase.method, testcase.key, testcase.cacheControl, have)
+		}
+	}
+
+	if m, err := svc.Stat(ctx, k); err != nil || m.Lookups != 2 {
+		t.Errorf("Stat: want 2 lookups, have %d (%v)", m.Lookups, err)
+	}
+	if _, err := shorttransport.NewRedirectHandler(eps, log.NewNopLogger(), shorttransport.WithRedirectCode(http.StatusOK)); err == nil {
+		t.Errorf("NewRedirectHandler: want error for code %d", http.StatusOK)
+	}
+}
+
+func TestHTTPAnalytics(t *testing.T) {
+	collector := shortservice.NewCollector(time.Hour, 10, 0, discard.NewCounter())
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(), shortservice.WithAnalytics(collector))
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram())
+	srv := httptest.NewServer(shorttransport.NewHTTPHandler(eps, log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	k, err := svc.Create(ctx, "http://a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ua := range []string{"Mozilla/5.0 (X11; Linux x86_64)", "Mozilla/5.0 (iPhone) Mobile/15E148"} {
+		req, _ := http.NewRequest("GET", srv.URL+"/api/"+k, nil)
+		req.Header.Set("Referer", "https://news.example.com/")
+		req.Header.Set("User-Agent", ua)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	collector.Close()
+
+	client, err := shorttransport.NewHTTPClient(srv.URL, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := client.Analytics(ctx, k, time.Now().Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Daily) != 1 || a.Daily[0].Lookups != 2 || a.Visitors != 2 {
+		t.Errorf("Analytics: want 2 lookups by 2 visitors, have %v by %d", a.Daily, a.Visitors)
+	}
+	if want := map[string]uint64{shortservice.AgentBrowser: 1, shortservice.AgentMobile: 1}; !reflect.DeepEqual(want, a.Agents) {
+		t.Errorf("Analytics: want agents %v, have %v", want, a.Agents)
+	}
+	if want := map[string]uint64{"news.example.com": 2}; !reflect.DeepEqual(want, a.Referrers) {
+		t.Errorf("Analytics: want referrers %v, have %v", want, a.Referrers)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/" + k + "/analytics?from=yesterday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusBadRequest, resp.StatusCode; want != have {
+		t.Errorf("GET analytics?from=yesterday: want %d, have %d", want, have)
+	}
+}
+
+func TestHTTPList(t *testing.T) {
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram())
+	srv := httptest.NewServer(shorttransport.NewHTTPHandler(eps, log.NewNopLogger()))
+	defer srv.Close()
+
+	ctx := context.Background()
+	for _, v := range []string{"http://a.com", "http://b.com", "http://c.com"} {
+		if _, err := svc.Create(ctx, v, shortservice.WithOwner("alice")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := svc.Create(ctx, "http://d.com", shortservice.WithOwner("bob")); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := shorttransport.NewHTTPClient(srv.URL, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []shortservice.Item
+	var cursor string
+	for {
+		page, err := client.List(ctx, cursor, 2, shortservice.Filter{Owner: "alice", From: time.Now().Add(-time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, page.Items...)
+		if cursor = page.Cursor; cursor == "" {
+			break
+		}
+	}
+	if want, have := 3, len(items); want != have {
+		t.Fatalf("List: want %d items, have %d", want, have)
+	}
+	for i, item := range items {
+		if item.Owner != "alice" || item.CreatedAt.IsZero() || (i > 0 && item.K <= items[i-1].K) {
+			t.Errorf("List: unexpected item %d %+v", i, item)
+		}
+	}
+	if _, err := client.List(ctx, "not a cursor!", 0, shortservice.Filter{}); err != shortservice.ErrInvalidCursor {
+		t.Errorf("List: want %v, have %v", shortservice.ErrInvalidCursor, err)
+	}
+
+	for query, want := range map[string]int{
+		"?prefix=http://d":  http.StatusOK,
+		"?limit=many":       http.StatusBadRequest,
+		"?limit=1001":       http.StatusBadRequest,
+		"?from=yesterday":   http.StatusBadRequest,
+		"?cursor=not%20one": http.StatusBadRequest,
+	} {
+		resp, err := http.Get(srv.URL + "/api" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if have := resp.StatusCode; want != have {
+			t.Errorf("GET /api%s: want %d, have %d (%s)", query, want, have, body)
+		}
+		if want == http.StatusOK && !strings.Contains(string(body), `"v":"http://d.com","owner":"bob"`) {
+			t.Errorf("GET /api%s: unexpected body %s", query, body)
+		}
+	}
+}
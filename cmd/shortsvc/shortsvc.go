@@ -1,21 +1,29 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/oklog/run"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/prometheus"
 	kitgrpc "github.com/go-kit/kit/transport/grpc"
@@ -32,7 +40,33 @@ func main() {
 		debugAddr = fs.String("debug.addr", ":8080", "Debug and metrics listen address")
 		httpAddr  = fs.String("http-addr", ":8081", "HTTP listen address")
 		grpcAddr  = fs.String("grpc-addr", ":8082", "gRPC listen address")
-		store     = fs.String("store", "inmem", "Storage backen type")
+		store     = fs.String("store", "inmem", "Storage backend type: inmem, file")
+		storeDir  = fs.String("store-dir", "data", "Data directory for the file storage backend")
+		compact   = fs.Duration("compact-interval", time.Minute, "How often the file storage backend compacts its log")
+		reap      = fs.Duration("reap-interval", time.Minute, "How often expired keys are removed from storage")
+		keygen    = fs.String("keygen", "md5", "Key generation strategy: md5, counter, random, hmac")
+		hmacFile  = fs.String("hmac-secret-file", "", "File of HMAC secrets, one per line and newest first, reloaded on SIGHUP (default $SHORTSVC_HMAC_SECRET, comma separated)")
+		alphabet  = fs.String("key-alphabet", "base64url", "Alphabet of generated keys: base64url, base62, crockford32, lowercase")
+		minKeyLen = fs.Int("key-min-length", 6, "Length of the shortest generated keys")
+		blockFile = fs.String("blocklist-file", "", "File of words keys must not contain, one per line, reloaded on SIGHUP")
+		canonical = fs.String("canonicalize", "", "Comma separated steps rewriting URL values before they are stored: case, port, path, query, tracking, or all; empty disables them")
+		trackings = fs.String("tracking-params", strings.Join(shortservice.DefaultTrackingParams, ","), "Comma separated query parameters removed by the tracking step, a trailing * matching any suffix")
+		logLevel  = fs.String("log-level", "info", "Lowest level logged: debug, info, warn, error")
+		redaction = fs.String("log-redaction", "host", "How much of values is logged: none, host, hash, full")
+		logSample = fs.Int("log-lookup-sample", 1, "Log one in every n lookups")
+		limitFile = fs.String("limits-file", "", "JSON file of rate limits and circuit breaker settings by method")
+		clientRPS = fs.Float64("client-rate", 0, "Requests per second allowed to each caller without a tier, 0 disables per-client limits")
+		clientBst = fs.Int("client-burst", 20, "Burst of requests allowed to each caller without a tier")
+		tiersFile = fs.String("client-tiers-file", "", "JSON file of per-client rate limit tiers and the callers in each")
+		clientLRU = fs.Int("client-cache-size", 10000, "How many callers per-client limits track at once")
+		proxyList = fs.String("trusted-proxies", "", "Comma separated addresses and CIDR ranges of proxies whose X-Client-Id header identifies callers")
+		keysFile  = fs.String("api-keys-file", "", "File of API keys and their scopes, one per line, reloaded on SIGHUP; empty disables authentication")
+		redirAddr = fs.String("redirect-addr", "", "Listen address of the public redirect handler; empty disables it")
+		redirCode = fs.Int("redirect-code", http.StatusFound, "HTTP status of redirects: 301, 302, 307, 308")
+		redirAge  = fs.Duration("redirect-max-age", 0, "How long browsers may cache redirects, 0 disables caching")
+		retention = fs.Duration("analytics-retention", 0, "How long lookup analytics are kept in memory, 0 disables them")
+		analytBuf = fs.Int("analytics-buffer", 10000, "How many lookups may wait to be recorded in analytics before they are dropped")
+		analytKey = fs.Int("analytics-max-keys", 100000, "How many keys analytics are kept for, about 1KB each a day, 0 for no limit")
 	)
 	fs.Usage = usageFor(fs, os.Args[0]+" [flags]")
 	fs.Parse(os.Args[1:])
@@ -40,26 +74,129 @@ func main() {
 	var logger log.Logger
 	{
 		logger = log.NewLogfmtLogger(os.Stderr)
+		switch *logLevel {
+		case "debug":
+			logger = level.NewFilter(logger, level.AllowDebug())
+		case "info":
+			logger = level.NewFilter(logger, level.AllowInfo())
+		case "warn":
+			logger = level.NewFilter(logger, level.AllowWarn())
+		case "error":
+			logger = level.NewFilter(logger, level.AllowError())
+		default:
+			logger.Log("during", "boot", "log-level", *logLevel, "err", "Unsupported log level")
+			os.Exit(1)
+		}
 		logger = log.With(logger, "ts", log.DefaultTimestampUTC)
 		logger = log.With(logger, "caller", log.DefaultCaller)
 	}
 
-	var inserts, lookups metrics.Counter
+	var logging []shortservice.LoggingOption
 	{
-		// Business-level metrics.
-		// TODO: Include count of key collisions here.
+		r, err := shortservice.ParseRedaction(*redaction)
+		if err != nil {
+			logger.Log("during", "boot", "log-redaction", *redaction, "err", err)
+			os.Exit(1)
+		}
+		logging = []shortservice.LoggingOption{
+			shortservice.WithRedaction(r),
+			shortservice.WithLookupSampling(*logSample),
+		}
+	}
+
+	var (
+		inserts, lookups, updates, deletes, blocked, dropped metrics.Counter
+		hitRatio                                             metrics.Gauge
+	)
+	{
+		// Business-level metrics. Calls are labelled with the kind of
+		// error they failed with, if any.
 		inserts = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
 			Namespace: "default",
 			Subsystem: "shortsvc",
 			Name:      "inserts",
 			Help:      "Total count of inserts.",
-		}, []string{"method", "success"})
+		}, []string{"method", "success", "error", "generator"})
 		lookups = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
 			Namespace: "default",
 			Subsystem: "shortsvc",
 			Name:      "lookups",
 			Help:      "Total count of lookups.",
-		}, []string{"method", "success"})
+		}, []string{"method", "success", "error"})
+		updates = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "updates",
+			Help:      "Total count of updates.",
+		}, []string{"method", "success", "error"})
+		deletes = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "deletes",
+			Help:      "Total count of deletes.",
+		}, []string{"method", "success", "error"})
+		blocked = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "blocked_keys",
+			Help:      "Total count of generated keys skipped by the blocklist.",
+		}, []string{})
+		dropped = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "analytics_dropped_lookups",
+			Help:      "Total count of lookups left out of analytics because the queue was full or too many keys were tracked.",
+		}, []string{})
+		hitRatio = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "lookup_hit_ratio",
+			Help:      "Share of looked up keys that were found.",
+		}, []string{})
+	}
+	var keyMetrics shortservice.KeyMetrics
+	{
+		// Key generation metrics. Shifts are the keys tried after the
+		// first one because they collided or were blocked.
+		keyMetrics.Shifts = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "key_shifts",
+			Help:      "Keys tried after the first one per created key.",
+			Buckets:   []float64{0, 1, 2, 4, 8, 16, 32, 64},
+		}, []string{})
+		keyMetrics.Grows = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "key_size_increases",
+			Help:      "Total count of moves to longer keys while searching for a free key.",
+		}, []string{})
+		keyMetrics.Lengths = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "key_length",
+			Help:      "Length of created keys.",
+			Buckets:   stdprometheus.LinearBuckets(4, 1, 13),
+		}, []string{})
+	}
+	var (
+		breakerState       metrics.Gauge
+		breakerTransitions metrics.Counter
+	)
+	{
+		// Circuit breaker metrics.
+		breakerState = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "breaker_state",
+			Help:      "State of the circuit breaker: 0 closed, 1 half open, 2 open.",
+		}, []string{"method"})
+		breakerTransitions = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "breaker_transitions",
+			Help:      "Total count of circuit breaker state changes.",
+		}, []string{"method", "from", "to"})
 	}
 	var duration metrics.Histogram
 	{
@@ -73,22 +210,208 @@ func main() {
 	}
 	http.DefaultServeMux.Handle("/metrics", promhttp.Handler())
 
-	var service shortservice.Service
+	var backend shortservice.Store
 	{
 		switch *store {
 		case "inmem":
-			logger.Log("Storage", store)
-			service = shortservice.NewInMemService(logger, inserts, lookups)
+			backend = shortservice.NewInMemStore()
+		case "file":
+			fileStore, err := shortservice.NewFileStore(*storeDir, *compact, log.With(logger, "store", "file"))
+			if err != nil {
+				logger.Log("during", "boot", "store", *store, "err", err)
+				os.Exit(1)
+			}
+			defer fileStore.Close()
+			backend = fileStore
 		default:
 			logger.Log("during", "boot", "store", *store, "err", "Unsupported storage type")
 			os.Exit(1)
 		}
+		logger.Log("storage", *store)
+
+		stdprometheus.MustRegister(stdprometheus.NewGaugeFunc(stdprometheus.GaugeOpts{
+			Namespace: "default",
+			Subsystem: "shortsvc",
+			Name:      "stored_keys",
+			Help:      "Number of keys in storage, including expired keys that are yet to be reaped.",
+		}, func() float64 {
+			n, err := backend.Len()
+			if err != nil {
+				return math.NaN()
+			}
+			return float64(n)
+		}))
+	}
+
+	var format shortservice.KeyFormat
+	{
+		a, err := shortservice.AlphabetByName(*alphabet)
+		if err != nil {
+			logger.Log("during", "boot", "key-alphabet", *alphabet, "err", err)
+			os.Exit(1)
+		}
+		format = shortservice.KeyFormat{Alphabet: a, MinSize: *minKeyLen}
+		if err := format.Validate(); err != nil {
+			logger.Log("during", "boot", "key-min-length", *minKeyLen, "err", err)
+			os.Exit(1)
+		}
 	}
 
 	var (
-		endpoints   = shortendpoint.New(service, logger, duration)
-		httpHandler = shorttransport.NewHTTPHandler(endpoints, logger)
-		grpcServer  = shorttransport.NewGRPCServer(endpoints, logger)
+		generator shortservice.KeyGenerator
+		reloaders []func() error // run on SIGHUP
+	)
+	{
+		switch *keygen {
+		case "md5":
+			generator = shortservice.NewMD5Generator(format)
+		case "counter":
+			// Carry on after the largest key issued before a restart, which
+			// deletes and reaps leave beyond the number of stored keys.
+			n, err := shortservice.CounterStart(backend, format)
+			if err != nil {
+				logger.Log("during", "boot", "keygen", *keygen, "err", err)
+				os.Exit(1)
+			}
+			generator = shortservice.NewCounterGenerator(format, n)
+		case "random":
+			generator = shortservice.NewRandomGenerator(format)
+		case "hmac":
+			secrets, err := loadSecrets(*hmacFile, "SHORTSVC_HMAC_SECRET")
+			if err != nil {
+				logger.Log("during", "boot", "keygen", *keygen, "err", err)
+				os.Exit(1)
+			}
+			hmacGenerator := shortservice.NewHMACGenerator(format, secrets[0], secrets[1:]...)
+			if *hmacFile != "" {
+				reloaders = append(reloaders, func() error {
+					secrets, err := loadSecrets(*hmacFile, "")
+					if err != nil {
+						return err
+					}
+					hmacGenerator.SetSecrets(secrets[0], secrets[1:]...)
+					logger.Log("during", "reload", "keygen", *keygen, "secrets", len(secrets))
+					return nil
+				})
+			}
+			generator = hmacGenerator
+		default:
+			logger.Log("during", "boot", "keygen", *keygen, "err", "Unsupported key generator")
+			os.Exit(1)
+		}
+		logger.Log("keygen", *keygen, "alphabet", format.Alphabet, "min-length", format.MinSize)
+	}
+
+	options := []shortservice.ServiceOption{
+		shortservice.WithKeyGenerator(generator),
+		shortservice.WithKeyMetrics(keyMetrics),
+		shortservice.WithChangeCounters(updates, deletes),
+		shortservice.WithHitRatio(hitRatio),
+		shortservice.WithLogging(logging...),
+	}
+	if *blockFile != "" {
+		blocklist, err := shortservice.LoadBlocklist(*blockFile)
+		if err != nil {
+			logger.Log("during", "boot", "blocklist", *blockFile, "err", err)
+			os.Exit(1)
+		}
+		reloaders = append(reloaders, func() error {
+			if err := blocklist.Reload(); err != nil {
+				return err
+			}
+			logger.Log("during", "reload", "blocklist", *blockFile, "words", blocklist.Len())
+			return nil
+		})
+		options = append(options, shortservice.WithBlocklist(blocklist, blocked))
+		logger.Log("blocklist", *blockFile, "words", blocklist.Len())
+	}
+
+	if *canonical != "" {
+		steps, err := shortservice.ParseCanonicalSteps(*canonical)
+		if err != nil {
+			logger.Log("during", "boot", "canonicalize", *canonical, "err", err)
+			os.Exit(1)
+		}
+		options = append(options, shortservice.WithCanonicalizer(shortservice.NewCanonicalizer(steps, strings.Split(*trackings, ",")...)))
+		logger.Log("canonicalize", steps, "tracking-params", *trackings)
+	}
+
+	if *retention > 0 {
+		collector := shortservice.NewCollector(*retention, *analytBuf, *analytKey, dropped)
+		defer collector.Close()
+		options = append(options, shortservice.WithAnalytics(collector))
+		logger.Log("analytics-retention", *retention, "analytics-max-keys", *analytKey)
+	}
+
+	var service shortservice.Service
+	{
+		service = shortservice.NewService(backend, logger, inserts.With("generator", *keygen), lookups, options...)
+	}
+
+	endpointOptions := []shortendpoint.Option{
+		shortendpoint.WithLogging(logging...),
+		shortendpoint.WithBreakerMetrics(breakerState, breakerTransitions),
+	}
+	if *clientRPS > 0 {
+		limits := shortendpoint.ClientLimits{
+			Default: shortendpoint.Tier{Rate: rate.Limit(*clientRPS), Burst: *clientBst},
+			Size:    *clientLRU,
+		}
+		if *tiersFile != "" {
+			var err error
+			if limits.Tiers, limits.Clients, err = loadTiers(*tiersFile); err != nil {
+				logger.Log("during", "boot", "client-tiers", *tiersFile, "err", err)
+				os.Exit(1)
+			}
+		}
+		endpointOptions = append(endpointOptions, shortendpoint.WithClientLimiter(shortendpoint.NewClientLimiter(limits)))
+		logger.Log("client-rate", *clientRPS, "client-burst", *clientBst, "tiers", len(limits.Tiers))
+	}
+	// Only the API requires keys, so the redirect handler gets its own set of
+	// endpoints without them.
+	var authOptions []shortendpoint.Option
+	if *keysFile != "" {
+		keys, err := shortendpoint.LoadKeys(*keysFile)
+		if err != nil {
+			logger.Log("during", "boot", "api-keys", *keysFile, "err", err)
+			os.Exit(1)
+		}
+		reloaders = append(reloaders, func() error {
+			if err := keys.Reload(); err != nil {
+				return err
+			}
+			logger.Log("during", "reload", "api-keys", *keysFile, "keys", keys.Len())
+			return nil
+		})
+		authOptions = append(authOptions, shortendpoint.WithAuth(keys))
+		logger.Log("api-keys", *keysFile, "keys", keys.Len())
+	}
+	if *limitFile != "" {
+		limits, err := loadLimits(*limitFile)
+		if err != nil {
+			logger.Log("during", "boot", "limits", *limitFile, "err", err)
+			os.Exit(1)
+		}
+		for method, l := range limits {
+			endpointOptions = append(endpointOptions, shortendpoint.WithLimits(method, l))
+		}
+	}
+
+	var transportOptions []shorttransport.ServerOption
+	if *proxyList != "" {
+		proxies, err := shorttransport.ParseTrustedProxies(*proxyList)
+		if err != nil {
+			logger.Log("during", "boot", "trusted-proxies", *proxyList, "err", err)
+			os.Exit(1)
+		}
+		transportOptions = append(transportOptions, shorttransport.WithTrustedProxies(proxies...))
+		logger.Log("trusted-proxies", *proxyList)
+	}
+
+	var (
+		endpoints   = shortendpoint.New(service, logger, duration, append(authOptions, endpointOptions...)...)
+		httpHandler = shorttransport.NewHTTPHandler(endpoints, logger, transportOptions...)
+		grpcServer  = shorttransport.NewGRPCServer(endpoints, logger, transportOptions...)
 	)
 
 	var g run.Group
@@ -137,6 +460,67 @@ func main() {
 			grpcListener.Close()
 		})
 	}
+	if *redirAddr != "" {
+		// The redirect listener mounts the public redirect handler. Its
+		// endpoints have their own limits and breakers, reported under
+		// method="RedirectLookup" so they don't mix with the API's.
+		redirectLogger := log.With(logger, "transport", "redirect/HTTP")
+		redirectOptions := append([]shortendpoint.Option{shortendpoint.WithMethodPrefix("Redirect")}, endpointOptions...)
+		redirectHandler, err := shorttransport.NewRedirectHandler(
+			shortendpoint.New(service, redirectLogger, duration, redirectOptions...),
+			redirectLogger,
+			shorttransport.WithRedirectCode(*redirCode),
+			shorttransport.WithMaxAge(*redirAge),
+		)
+		if err != nil {
+			logger.Log("transport", "redirect/HTTP", "during", "boot", "err", err)
+			os.Exit(1)
+		}
+		redirectListener, err := net.Listen("tcp", *redirAddr)
+		if err != nil {
+			logger.Log("transport", "redirect/HTTP", "during", "Listen", "err", err)
+			os.Exit(1)
+		}
+		g.Add(func() error {
+			logger.Log("transport", "redirect/HTTP", "addr", *redirAddr, "code", *redirCode)
+			return http.Serve(redirectListener, redirectHandler)
+		}, func(error) {
+			redirectListener.Close()
+		})
+	}
+	{
+		// The reaper removes expired entries so their keys can be reused.
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			return shortservice.Reap(ctx, backend, *reap, log.With(logger, "component", "reaper"))
+		}, func(error) {
+			cancel()
+		})
+	}
+	{
+		// Reloadable configuration is reloaded on SIGHUP. A failed reload
+		// keeps the previous configuration.
+		cancelReload := make(chan struct{})
+		g.Add(func() error {
+			c := make(chan os.Signal, 1)
+			signal.Notify(c, syscall.SIGHUP)
+			defer signal.Stop(c)
+			for {
+				select {
+				case <-c:
+					for _, reload := range reloaders {
+						if err := reload(); err != nil {
+							logger.Log("during", "reload", "err", err)
+						}
+					}
+				case <-cancelReload:
+					return nil
+				}
+			}
+		}, func(error) {
+			close(cancelReload)
+		})
+	}
 	{
 		cancelInterrupt := make(chan struct{})
 		g.Add(func() error {
@@ -155,6 +539,125 @@ func main() {
 	logger.Log("exit", g.Run())
 }
 
+// loadSecrets reads secrets, newest first, from file with one secret per
+// line, or from the comma separated env variable if file is empty. Blank
+// lines and lines starting with # are ignored.
+func loadSecrets(file, env string) ([][]byte, error) {
+	var lines []string
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		lines = strings.Split(string(b), "\n")
+	} else {
+		lines = strings.Split(os.Getenv(env), ",")
+	}
+
+	var secrets [][]byte
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		secrets = append(secrets, []byte(line))
+	}
+	if len(secrets) == 0 {
+		if file != "" {
+			return nil, fmt.Errorf("no secrets in %s", file)
+		}
+		return nil, fmt.Errorf("no secrets in $%s", env)
+	}
+	return secrets, nil
+}
+
+// loadLimits reads endpoint limits by method name from a JSON file such as
+//
+//	{"Create": {"rate": 20, "burst": 5, "failures": 10, "timeout": "30s"}}
+//
+// Methods and fields left out keep their defaults.
+func loadLimits(file string) (map[string]shortendpoint.Limits, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]struct {
+		Rate        *float64 `json:"rate"`
+		Burst       *int     `json:"burst"`
+		Failures    *uint32  `json:"failures"`
+		Timeout     string   `json:"timeout"`
+		MaxRequests *uint32  `json:"max_requests"`
+		Interval    string   `json:"interval"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	limits := shortendpoint.DefaultLimits()
+	for method, c := range config {
+		l, ok := limits[method]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown method %q", file, method)
+		}
+		if c.Rate != nil {
+			l.Rate = rate.Limit(*c.Rate)
+		}
+		if c.Burst != nil {
+			l.Burst = *c.Burst
+		}
+		if c.Failures != nil {
+			l.Failures = *c.Failures
+		}
+		if c.MaxRequests != nil {
+			l.MaxRequests = *c.MaxRequests
+		}
+		if c.Timeout != "" {
+			if l.Timeout, err = time.ParseDuration(c.Timeout); err != nil {
+				return nil, fmt.Errorf("%s: %s: %v", file, method, err)
+			}
+		}
+		if c.Interval != "" {
+			if l.Interval, err = time.ParseDuration(c.Interval); err != nil {
+				return nil, fmt.Errorf("%s: %s: %v", file, method, err)
+			}
+		}
+		limits[method] = l
+	}
+	return limits, nil
+}
+
+// loadTiers reads per-client rate limit tiers, and the tier of each caller,
+// from a JSON file such as
+//
+//	{"tiers": {"gold": {"rate": 100, "burst": 200}}, "clients": {"acme": "gold"}}
+func loadTiers(file string) (map[string]shortendpoint.Tier, map[string]string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	var config struct {
+		Tiers map[string]struct {
+			Rate  float64 `json:"rate"`
+			Burst int     `json:"burst"`
+		} `json:"tiers"`
+		Clients map[string]string `json:"clients"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	tiers := map[string]shortendpoint.Tier{}
+	for name, t := range config.Tiers {
+		tiers[name] = shortendpoint.Tier{Rate: rate.Limit(t.Rate), Burst: t.Burst}
+	}
+	for client, tier := range config.Clients {
+		if _, ok := tiers[tier]; !ok {
+			return nil, nil, fmt.Errorf("%s: unknown tier %q of client %q", file, tier, client)
+		}
+	}
+	return tiers, config.Clients, nil
+}
+
 func usageFor(fs *flag.FlagSet, short string) func() {
 	return func() {
 		fmt.Fprintf(os.Stderr, "USAGE\n")
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/ratelimit"
+
+	"github.com/sgarcez/short/pkg/shortendpoint"
+	"github.com/sgarcez/short/pkg/shortservice"
+)
+
+func TestLoadLimits(t *testing.T) {
+	f, err := ioutil.TempFile("", "limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"Create": {"rate": 0, "burst": 0, "failures": 3, "timeout": "10s"}}`)
+	f.Close()
+
+	limits, err := loadLimits(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := shortendpoint.Limits{Rate: 0, Burst: 0, Failures: 3, Timeout: 10 * time.Second}
+	if have := limits["Create"]; have != want {
+		t.Errorf("Create: want %+v, have %+v", want, have)
+	}
+	if want, have := shortendpoint.DefaultLimits()["Lookup"], limits["Lookup"]; have != want {
+		t.Errorf("Lookup: want default %+v, have %+v", want, have)
+	}
+
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	eps := shortendpoint.New(svc, log.NewNopLogger(), discard.NewHistogram(), shortendpoint.WithLimits("Create", limits["Create"]))
+	if _, err := eps.Create(context.Background(), "http://a.com"); err != ratelimit.ErrLimited {
+		t.Errorf("Create: want %v, have %v", ratelimit.ErrLimited, err)
+	}
+}
+
+func TestLoadLimitsUnknownMethod(t *testing.T) {
+	f, err := ioutil.TempFile("", "limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"Shorten": {"rate": 1}}`)
+	f.Close()
+
+	if _, err := loadLimits(f.Name()); err == nil {
+		t.Errorf("want error for unknown method")
+	}
+}
@@ -24,7 +24,13 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 // The create request creates a short key for a value.
 type CreateRequest struct {
-	V                    string   `protobuf:"bytes,1,opt,name=v,proto3" json:"v,omitempty"`
+	V string `protobuf:"bytes,1,opt,name=v,proto3" json:"v,omitempty"`
+	// TTL in seconds, zero never expires.
+	Ttl int64 `protobuf:"varint,2,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// Optional owner id.
+	Owner string `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	// Optional custom key, generated if empty.
+	Key                  string   `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -62,10 +68,31 @@ func (m *CreateRequest) GetV() string {
 	return ""
 }
 
+func (m *CreateRequest) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+func (m *CreateRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *CreateRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
 // The create response contains the created key.
+// Errors are returned as gRPC status codes.
 type CreateReply struct {
 	K                    string   `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
-	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -103,14 +130,7 @@ func (m *CreateReply) GetK() string {
 	return ""
 }
 
-func (m *CreateReply) GetErr() string {
-	if m != nil {
-		return m.Err
-	}
-	return ""
-}
-
-// The Lookup request contains the key.
+// The Lookup request contains a key.
 type LookupRequest struct {
 	K                    string   `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -150,10 +170,9 @@ func (m *LookupRequest) GetK() string {
 	return ""
 }
 
-// The Lookup response contains the result of the lookup.
+// The Lookup response contains the resulting value for the lookup.
 type LookupReply struct {
 	V                    string   `protobuf:"bytes,1,opt,name=v,proto3" json:"v,omitempty"`
-	Err                  string   `protobuf:"bytes,2,opt,name=err,proto3" json:"err,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -191,143 +210,1642 @@ func (m *LookupReply) GetV() string {
 	return ""
 }
 
-func (m *LookupReply) GetErr() string {
+// The Stat request contains a key.
+type StatRequest struct {
+	K                    string   `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatRequest) Reset()         { *m = StatRequest{} }
+func (m *StatRequest) String() string { return proto.CompactTextString(m) }
+func (*StatRequest) ProtoMessage()    {}
+func (*StatRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{4}
+}
+
+func (m *StatRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatRequest.Unmarshal(m, b)
+}
+func (m *StatRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatRequest.Marshal(b, m, deterministic)
+}
+func (m *StatRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatRequest.Merge(m, src)
+}
+func (m *StatRequest) XXX_Size() int {
+	return xxx_messageInfo_StatRequest.Size(m)
+}
+func (m *StatRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatRequest proto.InternalMessageInfo
+
+func (m *StatRequest) GetK() string {
 	if m != nil {
-		return m.Err
+		return m.K
 	}
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*CreateRequest)(nil), "pb.CreateRequest")
-	proto.RegisterType((*CreateReply)(nil), "pb.CreateReply")
-	proto.RegisterType((*LookupRequest)(nil), "pb.LookupRequest")
-	proto.RegisterType((*LookupReply)(nil), "pb.LookupReply")
+// The Stat response contains the metadata of a key.
+// Timestamps are in Unix nanoseconds, zero when unset.
+type StatReply struct {
+	Owner      string `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	CreatedAt  int64  `protobuf:"varint,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ExpiresAt  int64  `protobuf:"varint,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	AccessedAt int64  `protobuf:"varint,4,opt,name=accessed_at,json=accessedAt,proto3" json:"accessed_at,omitempty"`
+	Lookups    uint64 `protobuf:"varint,5,opt,name=lookups,proto3" json:"lookups,omitempty"`
+	// The value as given, when it was canonicalized into a different one.
+	Original             string   `protobuf:"bytes,7,opt,name=original,proto3" json:"original,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func init() { proto.RegisterFile("shortsvc.proto", fileDescriptor_ea5b1d546d95581e) }
+func (m *StatReply) Reset()         { *m = StatReply{} }
+func (m *StatReply) String() string { return proto.CompactTextString(m) }
+func (*StatReply) ProtoMessage()    {}
+func (*StatReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{5}
+}
 
-var fileDescriptor_ea5b1d546d95581e = []byte{
-	// 178 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2b, 0xce, 0xc8, 0x2f,
-	0x2a, 0x29, 0x2e, 0x4b, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2a, 0x48, 0x52, 0x92,
-	0xe5, 0xe2, 0x75, 0x2e, 0x4a, 0x4d, 0x2c, 0x49, 0x0d, 0x4a, 0x2d, 0x2c, 0x4d, 0x2d, 0x2e, 0x11,
-	0xe2, 0xe1, 0x62, 0x2c, 0x93, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0c, 0x62, 0x2c, 0x53, 0xd2, 0xe5,
-	0xe2, 0x86, 0x49, 0x17, 0xe4, 0x54, 0x82, 0x24, 0xb3, 0x61, 0x92, 0xd9, 0x42, 0x02, 0x5c, 0xcc,
-	0xa9, 0x45, 0x45, 0x12, 0x4c, 0x60, 0x3e, 0x88, 0x09, 0x32, 0xcd, 0x27, 0x3f, 0x3f, 0xbb, 0xb4,
-	0x00, 0xc9, 0x34, 0x84, 0x06, 0x90, 0x69, 0x30, 0x69, 0xa8, 0x69, 0x08, 0xab, 0x30, 0x4d, 0x33,
-	0xca, 0xe4, 0x62, 0x0f, 0x06, 0xb9, 0x38, 0x35, 0x4f, 0x48, 0x8f, 0x8b, 0x0d, 0xe2, 0x0e, 0x21,
-	0x41, 0xbd, 0x82, 0x24, 0x3d, 0x14, 0x27, 0x4b, 0xf1, 0x23, 0x0b, 0x15, 0xe4, 0x54, 0x2a, 0x31,
-	0x80, 0xd4, 0x43, 0x6c, 0x82, 0xa8, 0x47, 0x71, 0x14, 0x44, 0x3d, 0x92, 0x43, 0x94, 0x18, 0x92,
-	0xd8, 0xc0, 0x21, 0x62, 0x0c, 0x08, 0x00, 0x00, 0xff, 0xff, 0x7c, 0x4c, 0x73, 0x38, 0x23, 0x01,
-	0x00, 0x00,
+func (m *StatReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatReply.Unmarshal(m, b)
+}
+func (m *StatReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatReply.Marshal(b, m, deterministic)
+}
+func (m *StatReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatReply.Merge(m, src)
+}
+func (m *StatReply) XXX_Size() int {
+	return xxx_messageInfo_StatReply.Size(m)
+}
+func (m *StatReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatReply.DiscardUnknown(m)
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+var xxx_messageInfo_StatReply proto.InternalMessageInfo
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *StatReply) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
 
-// ShortenClient is the client API for Shorten service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type ShortenClient interface {
-	// Creates a short key for a value.
-	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*CreateReply, error)
-	// Looks up a value by its key
-	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupReply, error)
+func (m *StatReply) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
 }
 
-type shortenClient struct {
-	cc *grpc.ClientConn
+func (m *StatReply) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
 }
 
-func NewShortenClient(cc *grpc.ClientConn) ShortenClient {
-	return &shortenClient{cc}
+func (m *StatReply) GetAccessedAt() int64 {
+	if m != nil {
+		return m.AccessedAt
+	}
+	return 0
 }
 
-func (c *shortenClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*CreateReply, error) {
-	out := new(CreateReply)
-	err := c.cc.Invoke(ctx, "/pb.Shorten/Create", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *StatReply) GetLookups() uint64 {
+	if m != nil {
+		return m.Lookups
 	}
-	return out, nil
+	return 0
 }
 
-func (c *shortenClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupReply, error) {
-	out := new(LookupReply)
-	err := c.cc.Invoke(ctx, "/pb.Shorten/Lookup", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *StatReply) GetOriginal() string {
+	if m != nil {
+		return m.Original
 	}
-	return out, nil
+	return ""
 }
 
-// ShortenServer is the server API for Shorten service.
-type ShortenServer interface {
-	// Creates a short key for a value.
-	Create(context.Context, *CreateRequest) (*CreateReply, error)
-	// Looks up a value by its key
-	Lookup(context.Context, *LookupRequest) (*LookupReply, error)
+// The Update request contains a key and its new value.
+type UpdateRequest struct {
+	K                    string   `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
+	V                    string   `protobuf:"bytes,2,opt,name=v,proto3" json:"v,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func RegisterShortenServer(s *grpc.Server, srv ShortenServer) {
-	s.RegisterService(&_Shorten_serviceDesc, srv)
+func (m *UpdateRequest) Reset()         { *m = UpdateRequest{} }
+func (m *UpdateRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateRequest) ProtoMessage()    {}
+func (*UpdateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{6}
 }
 
-func _Shorten_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *UpdateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateRequest.Unmarshal(m, b)
+}
+func (m *UpdateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateRequest.Merge(m, src)
+}
+func (m *UpdateRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateRequest.Size(m)
+}
+func (m *UpdateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateRequest proto.InternalMessageInfo
+
+func (m *UpdateRequest) GetK() string {
+	if m != nil {
+		return m.K
 	}
-	if interceptor == nil {
-		return srv.(ShortenServer).Create(ctx, in)
+	return ""
+}
+
+func (m *UpdateRequest) GetV() string {
+	if m != nil {
+		return m.V
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Shorten/Create",
+	return ""
+}
+
+// The Update response is empty, errors are returned as gRPC status codes.
+type UpdateReply struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateReply) Reset()         { *m = UpdateReply{} }
+func (m *UpdateReply) String() string { return proto.CompactTextString(m) }
+func (*UpdateReply) ProtoMessage()    {}
+func (*UpdateReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{7}
+}
+
+func (m *UpdateReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateReply.Unmarshal(m, b)
+}
+func (m *UpdateReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateReply.Marshal(b, m, deterministic)
+}
+func (m *UpdateReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateReply.Merge(m, src)
+}
+func (m *UpdateReply) XXX_Size() int {
+	return xxx_messageInfo_UpdateReply.Size(m)
+}
+func (m *UpdateReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateReply proto.InternalMessageInfo
+
+// The Delete request contains a key.
+type DeleteRequest struct {
+	K                    string   `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
+func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteRequest) ProtoMessage()    {}
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{8}
+}
+
+func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteRequest.Unmarshal(m, b)
+}
+func (m *DeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteRequest.Merge(m, src)
+}
+func (m *DeleteRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteRequest.Size(m)
+}
+func (m *DeleteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteRequest proto.InternalMessageInfo
+
+func (m *DeleteRequest) GetK() string {
+	if m != nil {
+		return m.K
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ShortenServer).Create(ctx, req.(*CreateRequest))
+	return ""
+}
+
+// The Delete response is empty, errors are returned as gRPC status codes.
+type DeleteReply struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteReply) Reset()         { *m = DeleteReply{} }
+func (m *DeleteReply) String() string { return proto.CompactTextString(m) }
+func (*DeleteReply) ProtoMessage()    {}
+func (*DeleteReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{9}
+}
+
+func (m *DeleteReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteReply.Unmarshal(m, b)
+}
+func (m *DeleteReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteReply.Marshal(b, m, deterministic)
+}
+func (m *DeleteReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteReply.Merge(m, src)
+}
+func (m *DeleteReply) XXX_Size() int {
+	return xxx_messageInfo_DeleteReply.Size(m)
+}
+func (m *DeleteReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteReply proto.InternalMessageInfo
+
+// The CreateBatch request contains values to shorten and the options that
+// apply to all of them.
+type CreateBatchRequest struct {
+	Vs []string `protobuf:"bytes,1,rep,name=vs,proto3" json:"vs,omitempty"`
+	// TTL in seconds, zero never expires.
+	Ttl int64 `protobuf:"varint,2,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// Optional owner id.
+	Owner                string   `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateBatchRequest) Reset()         { *m = CreateBatchRequest{} }
+func (m *CreateBatchRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateBatchRequest) ProtoMessage()    {}
+func (*CreateBatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{10}
+}
+
+func (m *CreateBatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateBatchRequest.Unmarshal(m, b)
+}
+func (m *CreateBatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateBatchRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateBatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateBatchRequest.Merge(m, src)
+}
+func (m *CreateBatchRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateBatchRequest.Size(m)
+}
+func (m *CreateBatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateBatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateBatchRequest proto.InternalMessageInfo
+
+func (m *CreateBatchRequest) GetVs() []string {
+	if m != nil {
+		return m.Vs
 	}
-	return interceptor(ctx, in, info, handler)
+	return nil
 }
 
-func _Shorten_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(LookupRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *CreateBatchRequest) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
 	}
-	if interceptor == nil {
-		return srv.(ShortenServer).Lookup(ctx, in)
+	return 0
+}
+
+func (m *CreateBatchRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Shorten/Lookup",
+	return ""
+}
+
+// The CreateBatch response contains a result per value, in request order.
+type CreateBatchReply struct {
+	Results              []*BatchResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *CreateBatchReply) Reset()         { *m = CreateBatchReply{} }
+func (m *CreateBatchReply) String() string { return proto.CompactTextString(m) }
+func (*CreateBatchReply) ProtoMessage()    {}
+func (*CreateBatchReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{11}
+}
+
+func (m *CreateBatchReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateBatchReply.Unmarshal(m, b)
+}
+func (m *CreateBatchReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateBatchReply.Marshal(b, m, deterministic)
+}
+func (m *CreateBatchReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateBatchReply.Merge(m, src)
+}
+func (m *CreateBatchReply) XXX_Size() int {
+	return xxx_messageInfo_CreateBatchReply.Size(m)
+}
+func (m *CreateBatchReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateBatchReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateBatchReply proto.InternalMessageInfo
+
+func (m *CreateBatchReply) GetResults() []*BatchResult {
+	if m != nil {
+		return m.Results
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ShortenServer).Lookup(ctx, req.(*LookupRequest))
+	return nil
+}
+
+// The LookupBatch request contains keys.
+type LookupBatchRequest struct {
+	Ks                   []string `protobuf:"bytes,1,rep,name=ks,proto3" json:"ks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LookupBatchRequest) Reset()         { *m = LookupBatchRequest{} }
+func (m *LookupBatchRequest) String() string { return proto.CompactTextString(m) }
+func (*LookupBatchRequest) ProtoMessage()    {}
+func (*LookupBatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{12}
+}
+
+func (m *LookupBatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LookupBatchRequest.Unmarshal(m, b)
+}
+func (m *LookupBatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LookupBatchRequest.Marshal(b, m, deterministic)
+}
+func (m *LookupBatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LookupBatchRequest.Merge(m, src)
+}
+func (m *LookupBatchRequest) XXX_Size() int {
+	return xxx_messageInfo_LookupBatchRequest.Size(m)
+}
+func (m *LookupBatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LookupBatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LookupBatchRequest proto.InternalMessageInfo
+
+func (m *LookupBatchRequest) GetKs() []string {
+	if m != nil {
+		return m.Ks
 	}
-	return interceptor(ctx, in, info, handler)
+	return nil
 }
 
-var _Shorten_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "pb.Shorten",
-	HandlerType: (*ShortenServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Create",
-			Handler:    _Shorten_Create_Handler,
-		},
+// The LookupBatch response contains a result per key, in request order.
+type LookupBatchReply struct {
+	Results              []*BatchResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *LookupBatchReply) Reset()         { *m = LookupBatchReply{} }
+func (m *LookupBatchReply) String() string { return proto.CompactTextString(m) }
+func (*LookupBatchReply) ProtoMessage()    {}
+func (*LookupBatchReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{13}
+}
+
+func (m *LookupBatchReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LookupBatchReply.Unmarshal(m, b)
+}
+func (m *LookupBatchReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LookupBatchReply.Marshal(b, m, deterministic)
+}
+func (m *LookupBatchReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LookupBatchReply.Merge(m, src)
+}
+func (m *LookupBatchReply) XXX_Size() int {
+	return xxx_messageInfo_LookupBatchReply.Size(m)
+}
+func (m *LookupBatchReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_LookupBatchReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LookupBatchReply proto.InternalMessageInfo
+
+func (m *LookupBatchReply) GetResults() []*BatchResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+// A BatchResult is the outcome of a single item of a batch. Errors of the
+// whole batch are returned as gRPC status codes, err only describes this item.
+type BatchResult struct {
+	K                    string   `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
+	V                    string   `protobuf:"bytes,2,opt,name=v,proto3" json:"v,omitempty"`
+	Err                  string   `protobuf:"bytes,3,opt,name=err,proto3" json:"err,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchResult) Reset()         { *m = BatchResult{} }
+func (m *BatchResult) String() string { return proto.CompactTextString(m) }
+func (*BatchResult) ProtoMessage()    {}
+func (*BatchResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{14}
+}
+
+func (m *BatchResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchResult.Unmarshal(m, b)
+}
+func (m *BatchResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchResult.Marshal(b, m, deterministic)
+}
+func (m *BatchResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchResult.Merge(m, src)
+}
+func (m *BatchResult) XXX_Size() int {
+	return xxx_messageInfo_BatchResult.Size(m)
+}
+func (m *BatchResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchResult proto.InternalMessageInfo
+
+func (m *BatchResult) GetK() string {
+	if m != nil {
+		return m.K
+	}
+	return ""
+}
+
+func (m *BatchResult) GetV() string {
+	if m != nil {
+		return m.V
+	}
+	return ""
+}
+
+func (m *BatchResult) GetErr() string {
+	if m != nil {
+		return m.Err
+	}
+	return ""
+}
+
+// The Find request contains a value.
+type FindRequest struct {
+	V                    string   `protobuf:"bytes,1,opt,name=v,proto3" json:"v,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FindRequest) Reset()         { *m = FindRequest{} }
+func (m *FindRequest) String() string { return proto.CompactTextString(m) }
+func (*FindRequest) ProtoMessage()    {}
+func (*FindRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{15}
+}
+
+func (m *FindRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FindRequest.Unmarshal(m, b)
+}
+func (m *FindRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FindRequest.Marshal(b, m, deterministic)
+}
+func (m *FindRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FindRequest.Merge(m, src)
+}
+func (m *FindRequest) XXX_Size() int {
+	return xxx_messageInfo_FindRequest.Size(m)
+}
+func (m *FindRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FindRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FindRequest proto.InternalMessageInfo
+
+func (m *FindRequest) GetV() string {
+	if m != nil {
+		return m.V
+	}
+	return ""
+}
+
+// The Find response contains the key of the value.
+type FindReply struct {
+	K                    string   `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FindReply) Reset()         { *m = FindReply{} }
+func (m *FindReply) String() string { return proto.CompactTextString(m) }
+func (*FindReply) ProtoMessage()    {}
+func (*FindReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{16}
+}
+
+func (m *FindReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FindReply.Unmarshal(m, b)
+}
+func (m *FindReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FindReply.Marshal(b, m, deterministic)
+}
+func (m *FindReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FindReply.Merge(m, src)
+}
+func (m *FindReply) XXX_Size() int {
+	return xxx_messageInfo_FindReply.Size(m)
+}
+func (m *FindReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_FindReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FindReply proto.InternalMessageInfo
+
+func (m *FindReply) GetK() string {
+	if m != nil {
+		return m.K
+	}
+	return ""
+}
+
+// The Import request contains a single value of an import stream.
+// Consecutive values with the same options are created in a single batch.
+type ImportRequest struct {
+	V string `protobuf:"bytes,1,opt,name=v,proto3" json:"v,omitempty"`
+	// TTL in seconds, zero never expires.
+	Ttl int64 `protobuf:"varint,2,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// Optional owner id.
+	Owner                string   `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ImportRequest) Reset()         { *m = ImportRequest{} }
+func (m *ImportRequest) String() string { return proto.CompactTextString(m) }
+func (*ImportRequest) ProtoMessage()    {}
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{17}
+}
+
+func (m *ImportRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportRequest.Unmarshal(m, b)
+}
+func (m *ImportRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportRequest.Marshal(b, m, deterministic)
+}
+func (m *ImportRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportRequest.Merge(m, src)
+}
+func (m *ImportRequest) XXX_Size() int {
+	return xxx_messageInfo_ImportRequest.Size(m)
+}
+func (m *ImportRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportRequest proto.InternalMessageInfo
+
+func (m *ImportRequest) GetV() string {
+	if m != nil {
+		return m.V
+	}
+	return ""
+}
+
+func (m *ImportRequest) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+func (m *ImportRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+// The Analytics request contains a key and a time range.
+// Timestamps are in Unix nanoseconds. A zero from starts at the beginning of
+// the retention period, and a zero to ends now.
+type AnalyticsRequest struct {
+	K                    string   `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
+	From                 int64    `protobuf:"varint,2,opt,name=from,proto3" json:"from,omitempty"`
+	To                   int64    `protobuf:"varint,3,opt,name=to,proto3" json:"to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AnalyticsRequest) Reset()         { *m = AnalyticsRequest{} }
+func (m *AnalyticsRequest) String() string { return proto.CompactTextString(m) }
+func (*AnalyticsRequest) ProtoMessage()    {}
+func (*AnalyticsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{18}
+}
+
+func (m *AnalyticsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AnalyticsRequest.Unmarshal(m, b)
+}
+func (m *AnalyticsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AnalyticsRequest.Marshal(b, m, deterministic)
+}
+func (m *AnalyticsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AnalyticsRequest.Merge(m, src)
+}
+func (m *AnalyticsRequest) XXX_Size() int {
+	return xxx_messageInfo_AnalyticsRequest.Size(m)
+}
+func (m *AnalyticsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AnalyticsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AnalyticsRequest proto.InternalMessageInfo
+
+func (m *AnalyticsRequest) GetK() string {
+	if m != nil {
+		return m.K
+	}
+	return ""
+}
+
+func (m *AnalyticsRequest) GetFrom() int64 {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *AnalyticsRequest) GetTo() int64 {
+	if m != nil {
+		return m.To
+	}
+	return 0
+}
+
+// The Analytics response summarises the lookups of a key.
+type AnalyticsReply struct {
+	// Lookups per hour and per day in UTC, oldest first.
+	Hourly []*Bucket `protobuf:"bytes,1,rep,name=hourly,proto3" json:"hourly,omitempty"`
+	Daily  []*Bucket `protobuf:"bytes,2,rep,name=daily,proto3" json:"daily,omitempty"`
+	// Lookups by referrer host and by user agent class.
+	Referrers map[string]uint64 `protobuf:"bytes,3,rep,name=referrers,proto3" json:"referrers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Agents    map[string]uint64 `protobuf:"bytes,4,rep,name=agents,proto3" json:"agents,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// Estimated number of distinct visitors.
+	Visitors             uint64   `protobuf:"varint,5,opt,name=visitors,proto3" json:"visitors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AnalyticsReply) Reset()         { *m = AnalyticsReply{} }
+func (m *AnalyticsReply) String() string { return proto.CompactTextString(m) }
+func (*AnalyticsReply) ProtoMessage()    {}
+func (*AnalyticsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{19}
+}
+
+func (m *AnalyticsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AnalyticsReply.Unmarshal(m, b)
+}
+func (m *AnalyticsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AnalyticsReply.Marshal(b, m, deterministic)
+}
+func (m *AnalyticsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AnalyticsReply.Merge(m, src)
+}
+func (m *AnalyticsReply) XXX_Size() int {
+	return xxx_messageInfo_AnalyticsReply.Size(m)
+}
+func (m *AnalyticsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_AnalyticsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AnalyticsReply proto.InternalMessageInfo
+
+func (m *AnalyticsReply) GetHourly() []*Bucket {
+	if m != nil {
+		return m.Hourly
+	}
+	return nil
+}
+
+func (m *AnalyticsReply) GetDaily() []*Bucket {
+	if m != nil {
+		return m.Daily
+	}
+	return nil
+}
+
+func (m *AnalyticsReply) GetReferrers() map[string]uint64 {
+	if m != nil {
+		return m.Referrers
+	}
+	return nil
+}
+
+func (m *AnalyticsReply) GetAgents() map[string]uint64 {
+	if m != nil {
+		return m.Agents
+	}
+	return nil
+}
+
+func (m *AnalyticsReply) GetVisitors() uint64 {
+	if m != nil {
+		return m.Visitors
+	}
+	return 0
+}
+
+// A Bucket counts the lookups in the period beginning at start, in Unix
+// nanoseconds.
+type Bucket struct {
+	Start                int64    `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	Lookups              uint64   `protobuf:"varint,2,opt,name=lookups,proto3" json:"lookups,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Bucket) Reset()         { *m = Bucket{} }
+func (m *Bucket) String() string { return proto.CompactTextString(m) }
+func (*Bucket) ProtoMessage()    {}
+func (*Bucket) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{20}
+}
+
+func (m *Bucket) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Bucket.Unmarshal(m, b)
+}
+func (m *Bucket) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Bucket.Marshal(b, m, deterministic)
+}
+func (m *Bucket) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Bucket.Merge(m, src)
+}
+func (m *Bucket) XXX_Size() int {
+	return xxx_messageInfo_Bucket.Size(m)
+}
+func (m *Bucket) XXX_DiscardUnknown() {
+	xxx_messageInfo_Bucket.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Bucket proto.InternalMessageInfo
+
+func (m *Bucket) GetStart() int64 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *Bucket) GetLookups() uint64 {
+	if m != nil {
+		return m.Lookups
+	}
+	return 0
+}
+
+// The List request contains where to start listing, how many entries to
+// list and which of them. Zero fields select every entry.
+type ListRequest struct {
+	// Cursor of a previous page, empty to start from the first entry.
+	Cursor string `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// Maximum number of entries over all pages, zero for all of them.
+	Limit int64 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Owner id of the entries.
+	Owner string `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	// Prefix of the values of the entries.
+	Prefix string `protobuf:"bytes,4,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// Creation time range [from, to) in Unix nanoseconds.
+	From                 int64    `protobuf:"varint,5,opt,name=from,proto3" json:"from,omitempty"`
+	To                   int64    `protobuf:"varint,6,opt,name=to,proto3" json:"to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRequest) Reset()         { *m = ListRequest{} }
+func (m *ListRequest) String() string { return proto.CompactTextString(m) }
+func (*ListRequest) ProtoMessage()    {}
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{21}
+}
+
+func (m *ListRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRequest.Unmarshal(m, b)
+}
+func (m *ListRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRequest.Marshal(b, m, deterministic)
+}
+func (m *ListRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRequest.Merge(m, src)
+}
+func (m *ListRequest) XXX_Size() int {
+	return xxx_messageInfo_ListRequest.Size(m)
+}
+func (m *ListRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRequest proto.InternalMessageInfo
+
+func (m *ListRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *ListRequest) GetLimit() int64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *ListRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *ListRequest) GetFrom() int64 {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *ListRequest) GetTo() int64 {
+	if m != nil {
+		return m.To
+	}
+	return 0
+}
+
+// The List response is a page of entries, in key order.
+type ListReply struct {
+	Items []*ListItem `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	// Resumes the listing after this page, empty after the last one.
+	Cursor               string   `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListReply) Reset()         { *m = ListReply{} }
+func (m *ListReply) String() string { return proto.CompactTextString(m) }
+func (*ListReply) ProtoMessage()    {}
+func (*ListReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{22}
+}
+
+func (m *ListReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListReply.Unmarshal(m, b)
+}
+func (m *ListReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListReply.Marshal(b, m, deterministic)
+}
+func (m *ListReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListReply.Merge(m, src)
+}
+func (m *ListReply) XXX_Size() int {
+	return xxx_messageInfo_ListReply.Size(m)
+}
+func (m *ListReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListReply proto.InternalMessageInfo
+
+func (m *ListReply) GetItems() []*ListItem {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *ListReply) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+// A ListItem is an entry and its metadata. Timestamps are in Unix
+// nanoseconds, zero when unset.
+type ListItem struct {
+	K                    string   `protobuf:"bytes,1,opt,name=k,proto3" json:"k,omitempty"`
+	V                    string   `protobuf:"bytes,2,opt,name=v,proto3" json:"v,omitempty"`
+	Owner                string   `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	AccessedAt           int64    `protobuf:"varint,6,opt,name=accessed_at,json=accessedAt,proto3" json:"accessed_at,omitempty"`
+	Lookups              uint64   `protobuf:"varint,7,opt,name=lookups,proto3" json:"lookups,omitempty"`
+	Original             string   `protobuf:"bytes,8,opt,name=original,proto3" json:"original,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListItem) Reset()         { *m = ListItem{} }
+func (m *ListItem) String() string { return proto.CompactTextString(m) }
+func (*ListItem) ProtoMessage()    {}
+func (*ListItem) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ea5b1d546d95581e, []int{23}
+}
+
+func (m *ListItem) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListItem.Unmarshal(m, b)
+}
+func (m *ListItem) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListItem.Marshal(b, m, deterministic)
+}
+func (m *ListItem) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListItem.Merge(m, src)
+}
+func (m *ListItem) XXX_Size() int {
+	return xxx_messageInfo_ListItem.Size(m)
+}
+func (m *ListItem) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListItem.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListItem proto.InternalMessageInfo
+
+func (m *ListItem) GetK() string {
+	if m != nil {
+		return m.K
+	}
+	return ""
+}
+
+func (m *ListItem) GetV() string {
+	if m != nil {
+		return m.V
+	}
+	return ""
+}
+
+func (m *ListItem) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *ListItem) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *ListItem) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *ListItem) GetAccessedAt() int64 {
+	if m != nil {
+		return m.AccessedAt
+	}
+	return 0
+}
+
+func (m *ListItem) GetLookups() uint64 {
+	if m != nil {
+		return m.Lookups
+	}
+	return 0
+}
+
+func (m *ListItem) GetOriginal() string {
+	if m != nil {
+		return m.Original
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*CreateRequest)(nil), "pb.CreateRequest")
+	proto.RegisterType((*CreateReply)(nil), "pb.CreateReply")
+	proto.RegisterType((*LookupRequest)(nil), "pb.LookupRequest")
+	proto.RegisterType((*LookupReply)(nil), "pb.LookupReply")
+	proto.RegisterType((*StatRequest)(nil), "pb.StatRequest")
+	proto.RegisterType((*StatReply)(nil), "pb.StatReply")
+	proto.RegisterType((*UpdateRequest)(nil), "pb.UpdateRequest")
+	proto.RegisterType((*UpdateReply)(nil), "pb.UpdateReply")
+	proto.RegisterType((*DeleteRequest)(nil), "pb.DeleteRequest")
+	proto.RegisterType((*DeleteReply)(nil), "pb.DeleteReply")
+	proto.RegisterType((*CreateBatchRequest)(nil), "pb.CreateBatchRequest")
+	proto.RegisterType((*CreateBatchReply)(nil), "pb.CreateBatchReply")
+	proto.RegisterType((*LookupBatchRequest)(nil), "pb.LookupBatchRequest")
+	proto.RegisterType((*LookupBatchReply)(nil), "pb.LookupBatchReply")
+	proto.RegisterType((*BatchResult)(nil), "pb.BatchResult")
+	proto.RegisterType((*FindRequest)(nil), "pb.FindRequest")
+	proto.RegisterType((*FindReply)(nil), "pb.FindReply")
+	proto.RegisterType((*ImportRequest)(nil), "pb.ImportRequest")
+	proto.RegisterType((*AnalyticsRequest)(nil), "pb.AnalyticsRequest")
+	proto.RegisterType((*AnalyticsReply)(nil), "pb.AnalyticsReply")
+	proto.RegisterMapType((map[string]uint64)(nil), "pb.AnalyticsReply.AgentsEntry")
+	proto.RegisterMapType((map[string]uint64)(nil), "pb.AnalyticsReply.ReferrersEntry")
+	proto.RegisterType((*Bucket)(nil), "pb.Bucket")
+	proto.RegisterType((*ListRequest)(nil), "pb.ListRequest")
+	proto.RegisterType((*ListReply)(nil), "pb.ListReply")
+	proto.RegisterType((*ListItem)(nil), "pb.ListItem")
+}
+
+func init() { proto.RegisterFile("shortsvc.proto", fileDescriptor_ea5b1d546d95581e) }
+
+var fileDescriptor_ea5b1d546d95581e = []byte{
+	// 891 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0xef, 0x6e, 0xe3, 0x44,
+	0x10, 0xaf, 0x1d, 0xc7, 0x69, 0x26, 0x97, 0xa6, 0xac, 0xaa, 0xca, 0x18, 0x1d, 0xe4, 0x56, 0x7c,
+	0x08, 0x20, 0x45, 0xa7, 0x22, 0xc1, 0xf1, 0xe7, 0x84, 0x02, 0x77, 0xa0, 0x43, 0xfd, 0xe4, 0x13,
+	0xe2, 0x23, 0x72, 0xd3, 0xed, 0xd5, 0x8a, 0x13, 0x9b, 0xdd, 0x4d, 0xb8, 0x3c, 0x04, 0x6f, 0x83,
+	0x78, 0x0f, 0xde, 0x81, 0x07, 0x41, 0xb3, 0xe3, 0xb5, 0xd7, 0x4d, 0x1a, 0xa8, 0xf8, 0xe6, 0x99,
+	0xf9, 0xcd, 0xec, 0xec, 0xec, 0xcc, 0x6f, 0x0c, 0x27, 0xea, 0xb6, 0x90, 0x5a, 0x6d, 0xe6, 0xd3,
+	0x52, 0x16, 0xba, 0x60, 0x7e, 0x79, 0xc5, 0x7f, 0x86, 0xe1, 0x77, 0x52, 0xa4, 0x5a, 0x24, 0xe2,
+	0xd7, 0xb5, 0x50, 0x9a, 0x3d, 0x02, 0x6f, 0x13, 0x79, 0x63, 0x6f, 0xd2, 0x4f, 0xbc, 0x0d, 0x3b,
+	0x85, 0x8e, 0xd6, 0x79, 0xe4, 0x8f, 0xbd, 0x49, 0x27, 0xc1, 0x4f, 0x76, 0x06, 0xdd, 0xe2, 0xb7,
+	0x95, 0x90, 0x51, 0xc7, 0x60, 0x48, 0x40, 0xdc, 0x42, 0x6c, 0xa3, 0xc0, 0xe8, 0xf0, 0x93, 0x3f,
+	0x81, 0x81, 0x0d, 0x5c, 0xe6, 0x5b, 0x0c, 0xbb, 0xb0, 0x61, 0x17, 0x3f, 0x06, 0xc7, 0xfe, 0x69,
+	0x87, 0x3f, 0x86, 0xe1, 0x65, 0x51, 0x2c, 0xd6, 0xa5, 0x73, 0x76, 0x03, 0xc2, 0x08, 0xd6, 0x5c,
+	0x45, 0x68, 0x12, 0xab, 0x22, 0xbc, 0x07, 0x83, 0xd7, 0x3a, 0xd5, 0xfb, 0xfd, 0xff, 0xf4, 0xa0,
+	0x4f, 0x56, 0x74, 0xaf, 0xf3, 0xf6, 0xdc, 0xbc, 0x1f, 0x03, 0xcc, 0x4d, 0x96, 0xd7, 0xbf, 0xa4,
+	0xba, 0xba, 0x66, 0xbf, 0xd2, 0xcc, 0x34, 0x9a, 0xc5, 0xdb, 0x32, 0x93, 0x42, 0xa1, 0xb9, 0x43,
+	0xe6, 0x4a, 0x33, 0xd3, 0xec, 0x03, 0x18, 0xa4, 0xf3, 0xb9, 0x50, 0x8a, 0xdc, 0x03, 0x63, 0x07,
+	0xab, 0x9a, 0x69, 0x16, 0x41, 0x2f, 0x37, 0x57, 0x50, 0x51, 0x77, 0xec, 0x4d, 0x82, 0xc4, 0x8a,
+	0x2c, 0x86, 0xe3, 0x42, 0x66, 0x6f, 0xb2, 0x55, 0x9a, 0x47, 0x3d, 0x93, 0x51, 0x2d, 0xf3, 0x4f,
+	0x60, 0xf8, 0x53, 0x79, 0xdd, 0x7e, 0x93, 0xe6, 0x5e, 0x54, 0x08, 0xbf, 0x2a, 0x04, 0x1f, 0xc2,
+	0xc0, 0x82, 0xcb, 0x7c, 0x8b, 0x35, 0x7d, 0x21, 0x72, 0x71, 0x8f, 0x2f, 0xa2, 0xad, 0x19, 0xd1,
+	0x97, 0xc0, 0xe8, 0x91, 0xbe, 0x4d, 0xf5, 0xfc, 0xd6, 0xba, 0x9c, 0x80, 0xbf, 0x51, 0x91, 0x37,
+	0xee, 0x4c, 0xfa, 0x89, 0xbf, 0x51, 0xff, 0xb5, 0x09, 0xf8, 0x73, 0x38, 0x6d, 0x45, 0xc3, 0xb2,
+	0x7f, 0x04, 0x3d, 0x29, 0xd4, 0x3a, 0xd7, 0x14, 0x70, 0x70, 0x31, 0x9a, 0x96, 0x57, 0xd3, 0x0a,
+	0x80, 0xfa, 0xc4, 0xda, 0xf9, 0x87, 0xc0, 0xe8, 0xbd, 0xef, 0x26, 0xb3, 0xa8, 0x93, 0x59, 0x28,
+	0x3c, 0xa4, 0x85, 0x7a, 0xe0, 0x21, 0x5f, 0xc1, 0xc0, 0xd1, 0x1f, 0xaa, 0x2c, 0x5e, 0x5b, 0x48,
+	0x7b, 0x45, 0xfc, 0xc4, 0x76, 0xfb, 0x3e, 0x5b, 0x5d, 0xef, 0x1d, 0x15, 0xfe, 0x2e, 0xf4, 0xc9,
+	0xb8, 0xd3, 0xee, 0xfc, 0x25, 0x0c, 0x5f, 0x2d, 0xcb, 0x42, 0xea, 0xff, 0x35, 0x64, 0xfc, 0x05,
+	0x9c, 0xce, 0x56, 0x69, 0xbe, 0xd5, 0xd9, 0x5c, 0xed, 0x6f, 0x0d, 0x06, 0xc1, 0x8d, 0x2c, 0x96,
+	0x55, 0x28, 0xf3, 0x8d, 0x05, 0xd4, 0x45, 0xd5, 0xbb, 0xbe, 0x2e, 0xf8, 0xdf, 0x3e, 0x9c, 0x38,
+	0x61, 0x30, 0x5b, 0x0e, 0xe1, 0x6d, 0xb1, 0x96, 0xf9, 0xb6, 0x2a, 0x1f, 0x98, 0xf2, 0xad, 0xe7,
+	0x0b, 0xa1, 0x93, 0xca, 0xc2, 0xc6, 0xd0, 0xbd, 0x4e, 0xb3, 0x7c, 0x1b, 0xf9, 0x3b, 0x10, 0x32,
+	0xb0, 0x6f, 0xa0, 0x2f, 0xc5, 0x8d, 0x90, 0x52, 0x48, 0x15, 0x75, 0x0c, 0xea, 0x09, 0xa2, 0xda,
+	0x87, 0x4d, 0x13, 0x8b, 0x79, 0xb9, 0xd2, 0x72, 0x9b, 0x34, 0x3e, 0xec, 0x33, 0x08, 0xd3, 0x37,
+	0x62, 0xa5, 0x55, 0x14, 0x18, 0xef, 0xf7, 0xf7, 0x78, 0xcf, 0x0c, 0x80, 0x5c, 0x2b, 0x34, 0xce,
+	0xd2, 0x26, 0x53, 0x99, 0x2e, 0xa4, 0x1d, 0xb3, 0x5a, 0x8e, 0xbf, 0x86, 0x93, 0xf6, 0x81, 0x96,
+	0xaa, 0xbc, 0x9a, 0xaa, 0xb0, 0xda, 0x9b, 0x34, 0x5f, 0x0b, 0x53, 0xb6, 0x20, 0x21, 0xe1, 0x4b,
+	0xff, 0x99, 0x17, 0x7f, 0x01, 0x03, 0xe7, 0xc0, 0x87, 0xb8, 0xf2, 0x67, 0x10, 0x52, 0x79, 0x10,
+	0xa3, 0x74, 0x2a, 0xb5, 0xf1, 0xeb, 0x24, 0x24, 0xb8, 0xd4, 0xe0, 0xb7, 0xa8, 0x81, 0xff, 0xee,
+	0xc1, 0xe0, 0x32, 0x53, 0x75, 0xb3, 0x9c, 0x43, 0x38, 0x5f, 0x4b, 0x55, 0x58, 0xea, 0xaa, 0x24,
+	0x8c, 0x9b, 0x67, 0xcb, 0xcc, 0xd2, 0x16, 0x09, 0xf7, 0xf0, 0xf3, 0x39, 0x84, 0xa5, 0x14, 0x37,
+	0xd9, 0xdb, 0x8a, 0xa2, 0x2b, 0xa9, 0x6e, 0x98, 0xee, 0x4e, 0xc3, 0x84, 0x75, 0xc3, 0xfc, 0x00,
+	0x7d, 0x4a, 0x87, 0x5a, 0xa5, 0x9b, 0x69, 0xb1, 0xb4, 0x83, 0xf6, 0x08, 0x9f, 0x08, 0xad, 0xaf,
+	0xb4, 0x58, 0x26, 0x64, 0x72, 0x12, 0xf6, 0xdd, 0x84, 0xf9, 0x5f, 0x1e, 0x1c, 0x5b, 0xec, 0xc1,
+	0xc9, 0xdb, 0x7f, 0x87, 0x36, 0x57, 0x07, 0x87, 0xb9, 0xba, 0xfb, 0x2f, 0x5c, 0x1d, 0x1e, 0xe2,
+	0xea, 0xde, 0xfd, 0x5c, 0x7d, 0xdc, 0xe6, 0xea, 0x8b, 0x3f, 0x02, 0xe8, 0xbd, 0xc6, 0xb5, 0x2a,
+	0x56, 0x6c, 0x0a, 0x21, 0xf1, 0x1f, 0x7b, 0x07, 0xcb, 0xd2, 0xda, 0xab, 0xf1, 0xc8, 0x55, 0x21,
+	0xf7, 0x1e, 0x21, 0x9e, 0xa8, 0x8c, 0xf0, 0xad, 0x5d, 0x18, 0x8f, 0x5c, 0x15, 0xe1, 0x27, 0x10,
+	0xe0, 0x3e, 0x63, 0xc6, 0xe4, 0xec, 0xbd, 0x78, 0xd8, 0x28, 0xea, 0xc8, 0xb4, 0x14, 0x28, 0x72,
+	0x6b, 0x9b, 0xc4, 0x23, 0x57, 0x55, 0xe3, 0x69, 0x2d, 0x10, 0xbe, 0xb5, 0x41, 0xe2, 0x91, 0xab,
+	0x22, 0xfc, 0x73, 0xbb, 0xdc, 0x0d, 0x97, 0xb2, 0xf3, 0xe6, 0x6e, 0x2e, 0x77, 0xc7, 0x67, 0x3b,
+	0xfa, 0xda, 0xdd, 0xe1, 0x70, 0x72, 0xdf, 0xa5, 0xfe, 0xf8, 0x6c, 0x47, 0x5f, 0xd7, 0x01, 0x99,
+	0x96, 0xea, 0xe0, 0x10, 0x72, 0x3c, 0x6c, 0x14, 0x84, 0xfc, 0x1c, 0xfa, 0x35, 0x7f, 0xb0, 0xb3,
+	0x3b, 0x74, 0x42, 0x3e, 0x6c, 0x97, 0x64, 0xf8, 0x11, 0xbb, 0x80, 0x90, 0x18, 0x9b, 0x0a, 0xd2,
+	0x62, 0xef, 0xf8, 0xee, 0x76, 0xe1, 0x47, 0x13, 0xef, 0xa9, 0xc7, 0x3e, 0x86, 0x00, 0xbb, 0x9b,
+	0xd2, 0x72, 0x06, 0x38, 0x1e, 0x36, 0x0a, 0x13, 0xfd, 0xa9, 0x77, 0x15, 0x9a, 0x3f, 0xb0, 0x4f,
+	0xff, 0x19, 0x00, 0x32, 0x44, 0xdc, 0xd7, 0x93, 0x09, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// ShortenClient is the client API for Shorten service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ShortenClient interface {
+	// Creates a short key for a value.
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*CreateReply, error)
+	// Looks up a value by its key
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupReply, error)
+	// Returns the metadata of a key
+	Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatReply, error)
+	// Replaces the value of a key
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateReply, error)
+	// Deletes a key
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteReply, error)
+	// Creates short keys for a batch of values
+	CreateBatch(ctx context.Context, in *CreateBatchRequest, opts ...grpc.CallOption) (*CreateBatchReply, error)
+	// Looks up a batch of keys
+	LookupBatch(ctx context.Context, in *LookupBatchRequest, opts ...grpc.CallOption) (*LookupBatchReply, error)
+	// Finds the key of a value without creating it
+	Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindReply, error)
+	// Returns the lookup analytics of a key over a time range
+	Analytics(ctx context.Context, in *AnalyticsRequest, opts ...grpc.CallOption) (*AnalyticsReply, error)
+	// Creates short keys for a stream of values, streaming back a result per
+	// value in the order they were sent
+	Import(ctx context.Context, opts ...grpc.CallOption) (Shorten_ImportClient, error)
+	// Lists entries in key order, streaming them back a page at a time
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (Shorten_ListClient, error)
+}
+
+type shortenClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewShortenClient(cc *grpc.ClientConn) ShortenClient {
+	return &shortenClient{cc}
+}
+
+func (c *shortenClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*CreateReply, error) {
+	out := new(CreateReply)
+	err := c.cc.Invoke(ctx, "/pb.Shorten/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupReply, error) {
+	out := new(LookupReply)
+	err := c.cc.Invoke(ctx, "/pb.Shorten/Lookup", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatReply, error) {
+	out := new(StatReply)
+	err := c.cc.Invoke(ctx, "/pb.Shorten/Stat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateReply, error) {
+	out := new(UpdateReply)
+	err := c.cc.Invoke(ctx, "/pb.Shorten/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteReply, error) {
+	out := new(DeleteReply)
+	err := c.cc.Invoke(ctx, "/pb.Shorten/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) CreateBatch(ctx context.Context, in *CreateBatchRequest, opts ...grpc.CallOption) (*CreateBatchReply, error) {
+	out := new(CreateBatchReply)
+	err := c.cc.Invoke(ctx, "/pb.Shorten/CreateBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) LookupBatch(ctx context.Context, in *LookupBatchRequest, opts ...grpc.CallOption) (*LookupBatchReply, error) {
+	out := new(LookupBatchReply)
+	err := c.cc.Invoke(ctx, "/pb.Shorten/LookupBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindReply, error) {
+	out := new(FindReply)
+	err := c.cc.Invoke(ctx, "/pb.Shorten/Find", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) Analytics(ctx context.Context, in *AnalyticsRequest, opts ...grpc.CallOption) (*AnalyticsReply, error) {
+	out := new(AnalyticsReply)
+	err := c.cc.Invoke(ctx, "/pb.Shorten/Analytics", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenClient) Import(ctx context.Context, opts ...grpc.CallOption) (Shorten_ImportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Shorten_serviceDesc.Streams[0], "/pb.Shorten/Import", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortenImportClient{stream}
+	return x, nil
+}
+
+type Shorten_ImportClient interface {
+	Send(*ImportRequest) error
+	Recv() (*BatchResult, error)
+	grpc.ClientStream
+}
+
+type shortenImportClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortenImportClient) Send(m *ImportRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *shortenImportClient) Recv() (*BatchResult, error) {
+	m := new(BatchResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *shortenClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (Shorten_ListClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Shorten_serviceDesc.Streams[1], "/pb.Shorten/List", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortenListClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Shorten_ListClient interface {
+	Recv() (*ListReply, error)
+	grpc.ClientStream
+}
+
+type shortenListClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortenListClient) Recv() (*ListReply, error) {
+	m := new(ListReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ShortenServer is the server API for Shorten service.
+type ShortenServer interface {
+	// Creates a short key for a value.
+	Create(context.Context, *CreateRequest) (*CreateReply, error)
+	// Looks up a value by its key
+	Lookup(context.Context, *LookupRequest) (*LookupReply, error)
+	// Returns the metadata of a key
+	Stat(context.Context, *StatRequest) (*StatReply, error)
+	// Replaces the value of a key
+	Update(context.Context, *UpdateRequest) (*UpdateReply, error)
+	// Deletes a key
+	Delete(context.Context, *DeleteRequest) (*DeleteReply, error)
+	// Creates short keys for a batch of values
+	CreateBatch(context.Context, *CreateBatchRequest) (*CreateBatchReply, error)
+	// Looks up a batch of keys
+	LookupBatch(context.Context, *LookupBatchRequest) (*LookupBatchReply, error)
+	// Finds the key of a value without creating it
+	Find(context.Context, *FindRequest) (*FindReply, error)
+	// Returns the lookup analytics of a key over a time range
+	Analytics(context.Context, *AnalyticsRequest) (*AnalyticsReply, error)
+	// Creates short keys for a stream of values, streaming back a result per
+	// value in the order they were sent
+	Import(Shorten_ImportServer) error
+	// Lists entries in key order, streaming them back a page at a time
+	List(*ListRequest, Shorten_ListServer) error
+}
+
+func RegisterShortenServer(s *grpc.Server, srv ShortenServer) {
+	s.RegisterService(&_Shorten_serviceDesc, srv)
+}
+
+func _Shorten_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Shorten/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Shorten/Lookup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).Stat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Shorten/Stat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).Stat(ctx, req.(*StatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Shorten/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Shorten/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_CreateBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).CreateBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Shorten/CreateBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).CreateBatch(ctx, req.(*CreateBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_LookupBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).LookupBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Shorten/LookupBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).LookupBatch(ctx, req.(*LookupBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_Find_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).Find(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Shorten/Find",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).Find(ctx, req.(*FindRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_Analytics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnalyticsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenServer).Analytics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Shorten/Analytics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenServer).Analytics(ctx, req.(*AnalyticsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shorten_Import_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortenServer).Import(&shortenImportServer{stream})
+}
+
+type Shorten_ImportServer interface {
+	Send(*BatchResult) error
+	Recv() (*ImportRequest, error)
+	grpc.ServerStream
+}
+
+type shortenImportServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortenImportServer) Send(m *BatchResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *shortenImportServer) Recv() (*ImportRequest, error) {
+	m := new(ImportRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Shorten_List_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShortenServer).List(m, &shortenListServer{stream})
+}
+
+type Shorten_ListServer interface {
+	Send(*ListReply) error
+	grpc.ServerStream
+}
+
+type shortenListServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortenListServer) Send(m *ListReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Shorten_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.Shorten",
+	HandlerType: (*ShortenServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Create",
+			Handler:    _Shorten_Create_Handler,
+		},
 		{
 			MethodName: "Lookup",
 			Handler:    _Shorten_Lookup_Handler,
 		},
+		{
+			MethodName: "Stat",
+			Handler:    _Shorten_Stat_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _Shorten_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Shorten_Delete_Handler,
+		},
+		{
+			MethodName: "CreateBatch",
+			Handler:    _Shorten_CreateBatch_Handler,
+		},
+		{
+			MethodName: "LookupBatch",
+			Handler:    _Shorten_LookupBatch_Handler,
+		},
+		{
+			MethodName: "Find",
+			Handler:    _Shorten_Find_Handler,
+		},
+		{
+			MethodName: "Analytics",
+			Handler:    _Shorten_Analytics_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Import",
+			Handler:       _Shorten_Import_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "List",
+			Handler:       _Shorten_List_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "shortsvc.proto",
 }
@@ -0,0 +1,190 @@
+package shortendpoint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+const tokenKey contextKey = iota + 1
+
+// WithToken returns a copy of ctx carrying the bearer token of the caller.
+// Transports set it before calling an endpoint.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// Token returns the bearer token of the caller carried by ctx, if any.
+func Token(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey).(string)
+	return token
+}
+
+var (
+	// ErrUnauthenticated is returned to callers without a known API key.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned to callers whose API key lacks the scope of
+	// the endpoint.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Scope is a permission granted to an API key.
+type Scope string
+
+// Scopes of the endpoints of a Set.
+const (
+	// ScopeCreate allows Create, CreateBatch and Import.
+	ScopeCreate Scope = "create"
+	// ScopeLookup allows Lookup, LookupBatch, Stat, Analytics, List and Find.
+	ScopeLookup Scope = "lookup"
+	// ScopeUpdate allows Update.
+	ScopeUpdate Scope = "update"
+	// ScopeDelete allows Delete.
+	ScopeDelete Scope = "delete"
+	// ScopeAll allows every endpoint.
+	ScopeAll Scope = "*"
+)
+
+var scopes = []Scope{ScopeCreate, ScopeLookup, ScopeUpdate, ScopeDelete, ScopeAll}
+
+// Keys holds API keys and the scopes granted to each. Only hashes of the
+// keys are kept in memory.
+type Keys struct {
+	file string
+	mtx  sync.RWMutex
+	keys map[[sha256.Size]byte][]Scope
+}
+
+// NewKeys returns Keys granting each key in keys its scopes.
+func NewKeys(keys map[string][]Scope) *Keys {
+	k := &Keys{}
+	k.set(keys)
+	return k
+}
+
+// LoadKeys returns the Keys listed in file, one per line as the key
+// followed by a comma separated list of scopes, such as
+//
+//	6c1f0b2a lookup
+//	9e4d7c13 create,lookup
+//
+// Blank lines and lines starting with # are ignored. Reload reads the file
+// again.
+func LoadKeys(file string) (*Keys, error) {
+	k := &Keys{file: file}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload replaces the keys with the contents of the file they were loaded
+// from. On error the current keys are kept. It does nothing for Keys that
+// weren't loaded from a file.
+func (k *Keys) Reload() error {
+	if k.file == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(k.file)
+	if err != nil {
+		return err
+	}
+
+	keys := map[string][]Scope{}
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: want a key and its scopes", k.file, i+1)
+		}
+		for _, s := range strings.Split(fields[1], ",") {
+			if !validScope(Scope(s)) {
+				return fmt.Errorf("%s:%d: unknown scope %q", k.file, i+1, s)
+			}
+			keys[fields[0]] = append(keys[fields[0]], Scope(s))
+		}
+	}
+	k.set(keys)
+	return nil
+}
+
+func (k *Keys) set(keys map[string][]Scope) {
+	hashed := make(map[[sha256.Size]byte][]Scope, len(keys))
+	for key, scopes := range keys {
+		hashed[sha256.Sum256([]byte(key))] = scopes
+	}
+
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	k.keys = hashed
+}
+
+// Len returns the number of keys.
+func (k *Keys) Len() int {
+	k.mtx.RLock()
+	defer k.mtx.RUnlock()
+	return len(k.keys)
+}
+
+// Authorize returns nil if key grants scope, ErrUnauthenticated if key is
+// unknown and ErrForbidden otherwise.
+func (k *Keys) Authorize(key string, scope Scope) error {
+	if key == "" {
+		return ErrUnauthenticated
+	}
+	k.mtx.RLock()
+	granted, ok := k.keys[sha256.Sum256([]byte(key))]
+	k.mtx.RUnlock()
+	if !ok {
+		return ErrUnauthenticated
+	}
+	for _, s := range granted {
+		if s == scope || s == ScopeAll {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+func validScope(s Scope) bool {
+	for _, scope := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyID returns the identity of callers authenticated with key, "key:"
+// followed by the first 16 hex digits of its SHA-256 hash, so per-client
+// limits and tiers never hold the key itself.
+func KeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:8])
+}
+
+// AuthMiddleware returns an endpoint middleware that only lets through
+// callers whose token in the context is a key in keys granting scope. The
+// caller is then identified by the KeyID of its key, whatever identity the
+// transport gave it.
+func AuthMiddleware(keys *Keys, scope Scope) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			token := Token(ctx)
+			if err := keys.Authorize(token, scope); err != nil {
+				return nil, err
+			}
+			return next(WithClientID(ctx, KeyID(token)), request)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package shortendpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "keys.txt")
+
+	if err := ioutil.WriteFile(file, []byte("# reader\nr1 lookup\nw1 create,lookup\n\nadmin *\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeys(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, testcase := range []struct {
+		key   string
+		scope Scope
+		want  error
+	}{
+		{"r1", ScopeLookup, nil},
+		{"r1", ScopeCreate, ErrForbidden},
+		{"w1", ScopeCreate, nil},
+		{"w1", ScopeDelete, ErrForbidden},
+		{"admin", ScopeDelete, nil},
+		{"nope", ScopeLookup, ErrUnauthenticated},
+		{"", ScopeLookup, ErrUnauthenticated},
+	} {
+		if have := keys.Authorize(testcase.key, testcase.scope); have != testcase.want {
+			t.Errorf("Authorize(%q, %s): want %v, have %v", testcase.key, testcase.scope, testcase.want, have)
+		}
+	}
+
+	if err := ioutil.WriteFile(file, []byte("r1 read\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Reload(); err == nil {
+		t.Errorf("Reload: want error for unknown scope")
+	}
+	if err := keys.Authorize("w1", ScopeCreate); err != nil {
+		t.Errorf("failed Reload: want previous keys kept, have %v", err)
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	// printf %s hello | sha256sum | cut -c1-16
+	if want, have := "key:2cf24dba5fb0a30e", KeyID("hello"); want != have {
+		t.Errorf("KeyID: want %q, have %q", want, have)
+	}
+}
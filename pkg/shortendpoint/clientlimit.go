@@ -0,0 +1,145 @@
+package shortendpoint
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/ratelimit"
+)
+
+type contextKey int
+
+const clientIDKey contextKey = iota
+
+// WithClientID returns a copy of ctx carrying the identity of the caller.
+// Transports set it before calling an endpoint.
+func WithClientID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, clientIDKey, id)
+}
+
+// ClientID returns the identity of the caller carried by ctx, if any.
+func ClientID(ctx context.Context) string {
+	id, _ := ctx.Value(clientIDKey).(string)
+	return id
+}
+
+// LimitedError is returned when a caller exceeds its quota. It reads like
+// ratelimit.ErrLimited, so clients see it as that.
+type LimitedError struct {
+	// RetryAfter is how long the caller should wait before retrying.
+	RetryAfter time.Duration
+}
+
+func (e *LimitedError) Error() string { return ratelimit.ErrLimited.Error() }
+
+// Tier is the request quota of a class of callers.
+type Tier struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+// ClientLimits configures a ClientLimiter.
+type ClientLimits struct {
+	// Default is the quota of callers without a tier.
+	Default Tier
+	// Tiers holds named quotas, and Clients the name of the tier of each
+	// caller identity.
+	Tiers   map[string]Tier
+	Clients map[string]string
+	// Size is how many callers are tracked at once. The least recently
+	// seen caller is forgotten, and starts again with a full bucket.
+	Size int
+}
+
+// ClientLimiter keeps a token bucket per caller identity, so a noisy
+// caller doesn't starve the others.
+type ClientLimiter struct {
+	limits ClientLimits
+
+	mtx     sync.Mutex
+	lru     *list.List // of *clientBucket, most recently seen first
+	buckets map[string]*list.Element
+}
+
+type clientBucket struct {
+	id      string
+	limiter *rate.Limiter
+}
+
+// NewClientLimiter returns a ClientLimiter with the given limits.
+func NewClientLimiter(limits ClientLimits) *ClientLimiter {
+	if limits.Size < 1 {
+		limits.Size = 1
+	}
+	return &ClientLimiter{
+		limits:  limits,
+		lru:     list.New(),
+		buckets: map[string]*list.Element{},
+	}
+}
+
+// Len returns the number of callers tracked.
+func (l *ClientLimiter) Len() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.lru.Len()
+}
+
+// limiter returns the bucket of the caller id, creating it if needed.
+func (l *ClientLimiter) limiter(id string) *rate.Limiter {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if e, ok := l.buckets[id]; ok {
+		l.lru.MoveToFront(e)
+		return e.Value.(*clientBucket).limiter
+	}
+
+	tier := l.limits.Default
+	if name, ok := l.limits.Clients[id]; ok {
+		if t, ok := l.limits.Tiers[name]; ok {
+			tier = t
+		}
+	}
+	b := &clientBucket{id: id, limiter: rate.NewLimiter(tier.Rate, tier.Burst)}
+	l.buckets[id] = l.lru.PushFront(b)
+	for l.lru.Len() > l.limits.Size {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.buckets, oldest.Value.(*clientBucket).id)
+	}
+	return b.limiter
+}
+
+// Allow reports whether the caller id may make a request now, or how long
+// it should wait otherwise.
+func (l *ClientLimiter) Allow(id string, now time.Time) (bool, time.Duration) {
+	r := l.limiter(id).ReserveN(now, 1)
+	if !r.OK() {
+		// The bucket can never hold a token.
+		return false, time.Second
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// ClientLimitingMiddleware returns an endpoint middleware that fails with a
+// *LimitedError when the caller in the context exceeds its quota in l.
+func ClientLimitingMiddleware(l *ClientLimiter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if ok, retryAfter := l.Allow(ClientID(ctx), time.Now()); !ok {
+				return nil, &LimitedError{RetryAfter: retryAfter}
+			}
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package shortendpoint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/discard"
+
+	"github.com/sgarcez/short/pkg/shortservice"
+)
+
+func TestClientLimiter(t *testing.T) {
+	l := NewClientLimiter(ClientLimits{
+		Default: Tier{Rate: 1, Burst: 1},
+		Tiers:   map[string]Tier{"gold": {Rate: 1, Burst: 3}},
+		Clients: map[string]string{"acme": "gold"},
+		Size:    2,
+	})
+	now := time.Now()
+
+	if ok, _ := l.Allow("a", now); !ok {
+		t.Fatalf("a: want first request allowed")
+	}
+	if ok, retryAfter := l.Allow("a", now); ok || retryAfter != time.Second {
+		t.Errorf("a: want second request limited for 1s, have %v %v", ok, retryAfter)
+	}
+	if ok, _ := l.Allow("b", now); !ok {
+		t.Errorf("b: want request allowed while a is limited")
+	}
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("acme", now); !ok {
+			t.Errorf("acme: want request %d allowed by its tier", i)
+		}
+	}
+
+	// acme evicted a, the least recently seen, which starts over.
+	if want, have := 2, l.Len(); want != have {
+		t.Errorf("Len: want %d, have %d", want, have)
+	}
+	if ok, _ := l.Allow("a", now); !ok {
+		t.Errorf("a: want request allowed after eviction")
+	}
+}
+
+func TestClientLimitingMiddleware(t *testing.T) {
+	l := NewClientLimiter(ClientLimits{Default: Tier{Rate: 1, Burst: 1}, Size: 10})
+	ep := ClientLimitingMiddleware(l)(func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	ctx := WithClientID(context.Background(), "a")
+
+	if _, err := ep(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ep(ctx, nil)
+	if e, ok := err.(*LimitedError); !ok || e.RetryAfter <= 0 {
+		t.Errorf("want *LimitedError with a retry delay, have %v", err)
+	}
+	if _, err := ep(WithClientID(context.Background(), "b"), nil); err != nil {
+		t.Errorf("b: want allowed, have %v", err)
+	}
+}
+
+func TestClientLimiterBreaker(t *testing.T) {
+	l := NewClientLimiter(ClientLimits{Default: Tier{Rate: 1, Burst: 1}, Size: 10})
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	set := New(svc, log.NewNopLogger(), discard.NewHistogram(),
+		WithClientLimiter(l),
+		WithLimits("Lookup", Limits{Rate: 1000, Burst: 1000, Failures: 1}),
+	)
+
+	noisy := WithClientID(context.Background(), "noisy")
+	for i := 0; i < 6; i++ {
+		set.Lookup(noisy, "gnzLDu")
+	}
+	if _, err := set.Lookup(noisy, "gnzLDu"); err == nil || err == shortservice.ErrKeyNotFound {
+		t.Fatalf("noisy: want limited, have %v", err)
+	}
+	// The breaker trips on the first failure, but limited calls aren't one.
+	quiet := WithClientID(context.Background(), "quiet")
+	if _, err := set.Lookup(quiet, "gnzLDu"); err != shortservice.ErrKeyNotFound {
+		t.Errorf("quiet: want %v, have %v", shortservice.ErrKeyNotFound, err)
+	}
+}
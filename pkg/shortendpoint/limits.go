@@ -0,0 +1,90 @@
+package shortendpoint
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/sony/gobreaker"
+
+	"github.com/go-kit/kit/circuitbreaker"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Limits configures the rate limiter and the circuit breaker of an
+// endpoint.
+type Limits struct {
+	// Rate is how many requests per second are allowed, and Burst how many
+	// of them may be made at once.
+	Rate  rate.Limit
+	Burst int
+
+	// Failures is how many consecutive failures trip the breaker. Zero
+	// trips it after more than 5.
+	Failures uint32
+	// Timeout is how long the breaker stays open before letting trial
+	// requests through. Zero means 60 seconds.
+	Timeout time.Duration
+	// MaxRequests is how many trial requests the half open breaker lets
+	// through. Zero means 1.
+	MaxRequests uint32
+	// Interval is how often the closed breaker clears its failure counts.
+	// Zero never clears them.
+	Interval time.Duration
+}
+
+// DefaultLimits returns the limits of every endpoint of a Set by method
+// name, unless configured otherwise.
+func DefaultLimits() map[string]Limits {
+	return map[string]Limits{
+		"Create":      {Rate: 50, Burst: 1},
+		"Lookup":      {Rate: 100, Burst: 500},
+		"Stat":        {Rate: 100, Burst: 500},
+		"Update":      {Rate: 50, Burst: 1},
+		"Delete":      {Rate: 50, Burst: 1},
+		"CreateBatch": {Rate: 5, Burst: 1},
+		"LookupBatch": {Rate: 10, Burst: 50},
+		"Analytics":   {Rate: 10, Burst: 50},
+		"List":        {Rate: 5, Burst: 10},
+		"Find":        {Rate: 100, Burst: 500},
+		"Import":      {Rate: 50, Burst: 1},
+		"ListStream":  {Rate: 5, Burst: 1},
+	}
+}
+
+// limiter returns the token bucket of the limits.
+func (l Limits) limiter() *rate.Limiter {
+	return rate.NewLimiter(l.Rate, l.Burst)
+}
+
+// settings returns the breaker settings of the limits.
+func (l Limits) settings(name string) gobreaker.Settings {
+	st := gobreaker.Settings{
+		Name:        name,
+		MaxRequests: l.MaxRequests,
+		Interval:    l.Interval,
+		Timeout:     l.Timeout,
+	}
+	if l.Failures > 0 {
+		st.ReadyToTrip = func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= l.Failures
+		}
+	}
+	return st
+}
+
+// breaker returns a circuit breaker middleware for the named endpoint that
+// logs its state changes and reports them to state and transitions.
+func breaker(name string, l Limits, logger log.Logger, state metrics.Gauge, transitions metrics.Counter) endpoint.Middleware {
+	st := l.settings(name)
+	st.OnStateChange = func(name string, from, to gobreaker.State) {
+		level.Warn(logger).Log("breaker", name, "from", from, "to", to)
+		state.With("method", name).Set(float64(to))
+		transitions.With("method", name, "from", from.String(), "to", to.String()).Add(1)
+	}
+	state.With("method", name).Set(float64(gobreaker.StateClosed))
+	return circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(st))
+}
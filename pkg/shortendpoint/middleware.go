@@ -7,7 +7,10 @@ import (
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/go-kit/kit/metrics"
+
+	"github.com/sgarcez/short/pkg/shortservice"
 )
 
 // InstrumentingMiddleware returns an endpoint middleware that records
@@ -26,16 +29,46 @@ func InstrumentingMiddleware(duration metrics.Histogram) endpoint.Middleware {
 }
 
 // LoggingMiddleware returns an endpoint middleware that logs the
-// duration of each invocation, and the resulting error, if any.
-func LoggingMiddleware(logger log.Logger) endpoint.Middleware {
+// duration of each invocation, and the resulting error, if any. Values in
+// requests and responses are redacted and invocations sampled as set by
+// opts, so sampling is meant for lookup endpoints. Errors are logged at
+// error level and everything else at info level.
+func LoggingMiddleware(logger log.Logger, opts ...shortservice.LoggingOption) endpoint.Middleware {
+	o := shortservice.NewLoggingOptions(opts...)
+	sampler := shortservice.NewSampler(o.LookupSampling)
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 
 			defer func(begin time.Time) {
-				logger.Log("transport_error", err, "took", time.Since(begin))
+				if err == nil && !sampler.Sample() {
+					return
+				}
+				keyvals := []interface{}{"transport_error", err, "took", time.Since(begin)}
+				if v, ok := value(request, response); ok {
+					keyvals = append([]interface{}{"v", o.Redaction.Redact(v)}, keyvals...)
+				}
+				if err != nil {
+					level.Error(logger).Log(keyvals...)
+					return
+				}
+				level.Info(logger).Log(keyvals...)
 			}(time.Now())
 			return next(ctx, request)
 
 		}
 	}
 }
+
+// value returns the value carried by request or response, if any.
+func value(request, response interface{}) (string, bool) {
+	switch req := request.(type) {
+	case CreateRequest:
+		return req.V, true
+	case UpdateRequest:
+		return req.V, true
+	}
+	if resp, ok := response.(LookupResponse); ok && resp.V != "" {
+		return resp.V, true
+	}
+	return "", false
+}
@@ -2,15 +2,13 @@ package shortendpoint
 
 import (
 	"context"
+	"math"
+	"time"
 
-	"golang.org/x/time/rate"
-
-	"github.com/sony/gobreaker"
-
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 	"github.com/go-kit/kit/ratelimit"
 
 	"github.com/sgarcez/short/pkg/shortservice"
@@ -22,37 +20,242 @@ import (
 type Set struct {
 	CreateEndpoint endpoint.Endpoint
 	LookupEndpoint endpoint.Endpoint
+	StatEndpoint   endpoint.Endpoint
+	UpdateEndpoint endpoint.Endpoint
+	DeleteEndpoint endpoint.Endpoint
+
+	CreateBatchEndpoint endpoint.Endpoint
+	LookupBatchEndpoint endpoint.Endpoint
+
+	AnalyticsEndpoint endpoint.Endpoint
+	ListEndpoint      endpoint.Endpoint
+	FindEndpoint      endpoint.Endpoint
+
+	// ImportEndpoint creates the batches of a streaming import. It takes
+	// CreateBatch requests, but waits for the rate limiter instead of
+	// failing, which slows the import stream down.
+	ImportEndpoint endpoint.Endpoint
+	// ListStreamEndpoint lists the pages of a streaming List. It takes List
+	// requests, but waits for the rate limiter instead of failing, which
+	// slows the stream down.
+	ListStreamEndpoint endpoint.Endpoint
+}
+
+// Option configures the endpoints of a Set.
+type Option func(*options)
+
+type options struct {
+	logging     []shortservice.LoggingOption
+	limits      map[string]Limits
+	state       metrics.Gauge
+	transitions metrics.Counter
+	clients     endpoint.Middleware
+	auth        func(Scope) endpoint.Middleware
+	prefix      string
+}
+
+// nop is an endpoint middleware that does nothing.
+func nop(next endpoint.Endpoint) endpoint.Endpoint { return next }
+
+// WithLogging configures the logging middleware of every endpoint. Lookup
+// sampling only applies to the Lookup and LookupBatch endpoints.
+func WithLogging(opts ...shortservice.LoggingOption) Option {
+	return func(o *options) { o.logging = opts }
+}
+
+// WithLimits sets the rate limiter and circuit breaker of the endpoint with
+// the given method name. Endpoints not configured use DefaultLimits.
+func WithLimits(method string, l Limits) Option {
+	return func(o *options) { o.limits[method] = l }
+}
+
+// WithClientLimiter makes every endpoint but the streaming Import and List
+// limit each caller to its quota in l, on top of the limits shared by all
+// callers. Callers are limited ahead of the circuit breakers, so a limited
+// caller doesn't count as a failure for the others.
+func WithClientLimiter(l *ClientLimiter) Option {
+	return func(o *options) { o.clients = ClientLimitingMiddleware(l) }
+}
+
+// WithAuth makes every endpoint require an API key in keys granting the
+// scope of the endpoint.
+func WithAuth(keys *Keys) Option {
+	return func(o *options) {
+		o.auth = func(scope Scope) endpoint.Middleware { return AuthMiddleware(keys, scope) }
+	}
+}
+
+// WithBreakerMetrics makes the circuit breakers report their state to
+// state, as 0 for closed, 1 for half open and 2 for open, and count their
+// state changes in transitions. Both are labelled with the method name.
+func WithBreakerMetrics(state metrics.Gauge, transitions metrics.Counter) Option {
+	return func(o *options) { o.state, o.transitions = state, transitions }
+}
+
+// WithMethodPrefix prefixes the method name of every endpoint in logs,
+// metrics and circuit breaker names, to tell apart Sets wrapping the same
+// service, such as the one behind a redirect handler. Limits are still
+// configured by the plain method name, though every Set has its own rate
+// limiters and circuit breakers.
+func WithMethodPrefix(prefix string) Option {
+	return func(o *options) { o.prefix = prefix }
 }
 
 // New returns a Set that wraps the provided server, and wires in all of the
 // expected endpoint middlewares via the various parameters.
-func New(svc shortservice.Service, logger log.Logger, duration metrics.Histogram) Set {
+func New(svc shortservice.Service, logger log.Logger, duration metrics.Histogram, opts ...Option) Set {
+	o := options{
+		limits:      DefaultLimits(),
+		state:       discard.NewGauge(),
+		transitions: discard.NewCounter(),
+		clients:     nop,
+		auth:        func(Scope) endpoint.Middleware { return nop },
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// Only lookups are sampled.
+	unsampled := append(append([]shortservice.LoggingOption(nil), o.logging...), shortservice.WithLookupSampling(1))
+
 	var createEndpoint endpoint.Endpoint
 	{
 		createEndpoint = MakeCreateEndpoint(svc)
-		createEndpoint = ratelimit.NewErroringLimiter(rate.NewLimiter(50, 1))(createEndpoint)
-		createEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(createEndpoint)
-		createEndpoint = LoggingMiddleware(log.With(logger, "method", "Create"))(createEndpoint)
-		createEndpoint = InstrumentingMiddleware(duration.With("method", "Create"))(createEndpoint)
+		createEndpoint = ratelimit.NewErroringLimiter(o.limits["Create"].limiter())(createEndpoint)
+		createEndpoint = breaker(o.prefix+"Create", o.limits["Create"], logger, o.state, o.transitions)(createEndpoint)
+		createEndpoint = o.clients(createEndpoint)
+		createEndpoint = o.auth(ScopeCreate)(createEndpoint)
+		createEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"Create"), unsampled...)(createEndpoint)
+		createEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"Create"))(createEndpoint)
 	}
 	var lookupEndpoint endpoint.Endpoint
 	{
 		lookupEndpoint = MakeLookupEndpoint(svc)
-		lookupEndpoint = ratelimit.NewErroringLimiter(rate.NewLimiter(100, 500))(lookupEndpoint)
-		lookupEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{}))(lookupEndpoint)
-		lookupEndpoint = LoggingMiddleware(log.With(logger, "method", "Lookup"))(lookupEndpoint)
-		lookupEndpoint = InstrumentingMiddleware(duration.With("method", "Lookup"))(lookupEndpoint)
+		lookupEndpoint = ratelimit.NewErroringLimiter(o.limits["Lookup"].limiter())(lookupEndpoint)
+		lookupEndpoint = breaker(o.prefix+"Lookup", o.limits["Lookup"], logger, o.state, o.transitions)(lookupEndpoint)
+		lookupEndpoint = o.clients(lookupEndpoint)
+		lookupEndpoint = o.auth(ScopeLookup)(lookupEndpoint)
+		lookupEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"Lookup"), o.logging...)(lookupEndpoint)
+		lookupEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"Lookup"))(lookupEndpoint)
+	}
+	var statEndpoint endpoint.Endpoint
+	{
+		statEndpoint = MakeStatEndpoint(svc)
+		statEndpoint = ratelimit.NewErroringLimiter(o.limits["Stat"].limiter())(statEndpoint)
+		statEndpoint = breaker(o.prefix+"Stat", o.limits["Stat"], logger, o.state, o.transitions)(statEndpoint)
+		statEndpoint = o.clients(statEndpoint)
+		statEndpoint = o.auth(ScopeLookup)(statEndpoint)
+		statEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"Stat"), unsampled...)(statEndpoint)
+		statEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"Stat"))(statEndpoint)
+	}
+	var updateEndpoint endpoint.Endpoint
+	{
+		updateEndpoint = MakeUpdateEndpoint(svc)
+		updateEndpoint = ratelimit.NewErroringLimiter(o.limits["Update"].limiter())(updateEndpoint)
+		updateEndpoint = breaker(o.prefix+"Update", o.limits["Update"], logger, o.state, o.transitions)(updateEndpoint)
+		updateEndpoint = o.clients(updateEndpoint)
+		updateEndpoint = o.auth(ScopeUpdate)(updateEndpoint)
+		updateEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"Update"), unsampled...)(updateEndpoint)
+		updateEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"Update"))(updateEndpoint)
+	}
+	var deleteEndpoint endpoint.Endpoint
+	{
+		deleteEndpoint = MakeDeleteEndpoint(svc)
+		deleteEndpoint = ratelimit.NewErroringLimiter(o.limits["Delete"].limiter())(deleteEndpoint)
+		deleteEndpoint = breaker(o.prefix+"Delete", o.limits["Delete"], logger, o.state, o.transitions)(deleteEndpoint)
+		deleteEndpoint = o.clients(deleteEndpoint)
+		deleteEndpoint = o.auth(ScopeDelete)(deleteEndpoint)
+		deleteEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"Delete"), unsampled...)(deleteEndpoint)
+		deleteEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"Delete"))(deleteEndpoint)
+	}
+	var createBatchEndpoint endpoint.Endpoint
+	{
+		createBatchEndpoint = MakeCreateBatchEndpoint(svc)
+		createBatchEndpoint = ratelimit.NewErroringLimiter(o.limits["CreateBatch"].limiter())(createBatchEndpoint)
+		createBatchEndpoint = breaker(o.prefix+"CreateBatch", o.limits["CreateBatch"], logger, o.state, o.transitions)(createBatchEndpoint)
+		createBatchEndpoint = o.clients(createBatchEndpoint)
+		createBatchEndpoint = o.auth(ScopeCreate)(createBatchEndpoint)
+		createBatchEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"CreateBatch"), unsampled...)(createBatchEndpoint)
+		createBatchEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"CreateBatch"))(createBatchEndpoint)
+	}
+	var lookupBatchEndpoint endpoint.Endpoint
+	{
+		lookupBatchEndpoint = MakeLookupBatchEndpoint(svc)
+		lookupBatchEndpoint = ratelimit.NewErroringLimiter(o.limits["LookupBatch"].limiter())(lookupBatchEndpoint)
+		lookupBatchEndpoint = breaker(o.prefix+"LookupBatch", o.limits["LookupBatch"], logger, o.state, o.transitions)(lookupBatchEndpoint)
+		lookupBatchEndpoint = o.clients(lookupBatchEndpoint)
+		lookupBatchEndpoint = o.auth(ScopeLookup)(lookupBatchEndpoint)
+		lookupBatchEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"LookupBatch"), o.logging...)(lookupBatchEndpoint)
+		lookupBatchEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"LookupBatch"))(lookupBatchEndpoint)
+	}
+	var analyticsEndpoint endpoint.Endpoint
+	{
+		analyticsEndpoint = MakeAnalyticsEndpoint(svc)
+		analyticsEndpoint = ratelimit.NewErroringLimiter(o.limits["Analytics"].limiter())(analyticsEndpoint)
+		analyticsEndpoint = breaker(o.prefix+"Analytics", o.limits["Analytics"], logger, o.state, o.transitions)(analyticsEndpoint)
+		analyticsEndpoint = o.clients(analyticsEndpoint)
+		analyticsEndpoint = o.auth(ScopeLookup)(analyticsEndpoint)
+		analyticsEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"Analytics"), unsampled...)(analyticsEndpoint)
+		analyticsEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"Analytics"))(analyticsEndpoint)
+	}
+	var listEndpoint endpoint.Endpoint
+	{
+		listEndpoint = MakeListEndpoint(svc)
+		listEndpoint = ratelimit.NewErroringLimiter(o.limits["List"].limiter())(listEndpoint)
+		listEndpoint = breaker(o.prefix+"List", o.limits["List"], logger, o.state, o.transitions)(listEndpoint)
+		listEndpoint = o.clients(listEndpoint)
+		listEndpoint = o.auth(ScopeLookup)(listEndpoint)
+		listEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"List"), unsampled...)(listEndpoint)
+		listEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"List"))(listEndpoint)
+	}
+	var findEndpoint endpoint.Endpoint
+	{
+		findEndpoint = MakeFindEndpoint(svc)
+		findEndpoint = ratelimit.NewErroringLimiter(o.limits["Find"].limiter())(findEndpoint)
+		findEndpoint = breaker(o.prefix+"Find", o.limits["Find"], logger, o.state, o.transitions)(findEndpoint)
+		findEndpoint = o.clients(findEndpoint)
+		findEndpoint = o.auth(ScopeLookup)(findEndpoint)
+		findEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"Find"), unsampled...)(findEndpoint)
+		findEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"Find"))(findEndpoint)
+	}
+	var importEndpoint endpoint.Endpoint
+	{
+		importEndpoint = MakeCreateBatchEndpoint(svc)
+		importEndpoint = ratelimit.NewDelayingLimiter(o.limits["Import"].limiter())(importEndpoint)
+		importEndpoint = breaker(o.prefix+"Import", o.limits["Import"], logger, o.state, o.transitions)(importEndpoint)
+		importEndpoint = o.auth(ScopeCreate)(importEndpoint)
+		importEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"Import"), unsampled...)(importEndpoint)
+		importEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"Import"))(importEndpoint)
+	}
+	var listStreamEndpoint endpoint.Endpoint
+	{
+		listStreamEndpoint = MakeListEndpoint(svc)
+		listStreamEndpoint = ratelimit.NewDelayingLimiter(o.limits["ListStream"].limiter())(listStreamEndpoint)
+		listStreamEndpoint = breaker(o.prefix+"ListStream", o.limits["ListStream"], logger, o.state, o.transitions)(listStreamEndpoint)
+		listStreamEndpoint = o.auth(ScopeLookup)(listStreamEndpoint)
+		listStreamEndpoint = LoggingMiddleware(log.With(logger, "method", o.prefix+"ListStream"), unsampled...)(listStreamEndpoint)
+		listStreamEndpoint = InstrumentingMiddleware(duration.With("method", o.prefix+"ListStream"))(listStreamEndpoint)
 	}
 	return Set{
-		CreateEndpoint: createEndpoint,
-		LookupEndpoint: lookupEndpoint,
+		CreateEndpoint:      createEndpoint,
+		LookupEndpoint:      lookupEndpoint,
+		StatEndpoint:        statEndpoint,
+		UpdateEndpoint:      updateEndpoint,
+		DeleteEndpoint:      deleteEndpoint,
+		CreateBatchEndpoint: createBatchEndpoint,
+		LookupBatchEndpoint: lookupBatchEndpoint,
+		AnalyticsEndpoint:   analyticsEndpoint,
+		ListEndpoint:        listEndpoint,
+		FindEndpoint:        findEndpoint,
+		ImportEndpoint:      importEndpoint,
+		ListStreamEndpoint:  listStreamEndpoint,
 	}
 }
 
 // Create implements the service interface, so Set may be used as a service.
 // This is primarily useful in the context of a client library.
-func (s Set) Create(ctx context.Context, v string) (string, error) {
-	resp, err := s.CreateEndpoint(ctx, CreateRequest{V: v})
+func (s Set) Create(ctx context.Context, v string, opts ...shortservice.CreateOption) (string, error) {
+	o := shortservice.NewCreateOptions(opts...)
+	resp, err := s.CreateEndpoint(ctx, CreateRequest{V: v, TTL: Seconds(o.TTL), Owner: o.Owner, Key: o.Key})
 	if err != nil {
 		return "", err
 	}
@@ -71,11 +274,111 @@ func (s Set) Lookup(ctx context.Context, k string) (string, error) {
 	return response.V, response.Err
 }
 
+// Stat implements the service interface, so Set may be used as a
+// service. This is primarily useful in the context of a client library.
+func (s Set) Stat(ctx context.Context, k string) (shortservice.Metadata, error) {
+	resp, err := s.StatEndpoint(ctx, StatRequest{K: k})
+	if err != nil {
+		return shortservice.Metadata{}, err
+	}
+	response := resp.(StatResponse)
+	return response.Metadata, response.Err
+}
+
+// Update implements the service interface, so Set may be used as a
+// service. This is primarily useful in the context of a client library.
+func (s Set) Update(ctx context.Context, k, v string) error {
+	resp, err := s.UpdateEndpoint(ctx, UpdateRequest{K: k, V: v})
+	if err != nil {
+		return err
+	}
+	response := resp.(UpdateResponse)
+	return response.Err
+}
+
+// Delete implements the service interface, so Set may be used as a
+// service. This is primarily useful in the context of a client library.
+func (s Set) Delete(ctx context.Context, k string) error {
+	resp, err := s.DeleteEndpoint(ctx, DeleteRequest{K: k})
+	if err != nil {
+		return err
+	}
+	response := resp.(DeleteResponse)
+	return response.Err
+}
+
+// CreateBatch implements the service interface, so Set may be used as a
+// service. This is primarily useful in the context of a client library.
+func (s Set) CreateBatch(ctx context.Context, vs []string, opts ...shortservice.CreateOption) ([]shortservice.Result, error) {
+	o := shortservice.NewCreateOptions(opts...)
+	if o.Key != "" {
+		return nil, shortservice.ErrInvalidKey // custom keys can't be batched
+	}
+	resp, err := s.CreateBatchEndpoint(ctx, CreateBatchRequest{Vs: vs, TTL: Seconds(o.TTL), Owner: o.Owner})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(CreateBatchResponse)
+	return response.Results, response.Err
+}
+
+// LookupBatch implements the service interface, so Set may be used as a
+// service. This is primarily useful in the context of a client library.
+func (s Set) LookupBatch(ctx context.Context, ks []string) ([]shortservice.Result, error) {
+	resp, err := s.LookupBatchEndpoint(ctx, LookupBatchRequest{Ks: ks})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(LookupBatchResponse)
+	return response.Results, response.Err
+}
+
+// Analytics implements the service interface, so Set may be used as a
+// service. This is primarily useful in the context of a client library.
+func (s Set) Analytics(ctx context.Context, k string, from, to time.Time) (shortservice.Analytics, error) {
+	resp, err := s.AnalyticsEndpoint(ctx, AnalyticsRequest{K: k, From: from, To: to})
+	if err != nil {
+		return shortservice.Analytics{}, err
+	}
+	response := resp.(AnalyticsResponse)
+	return response.Analytics, response.Err
+}
+
+// List implements the service interface, so Set may be used as a service.
+// This is primarily useful in the context of a client library.
+func (s Set) List(ctx context.Context, cursor string, limit int, filter shortservice.Filter) (shortservice.Page, error) {
+	resp, err := s.ListEndpoint(ctx, ListRequest{Cursor: cursor, Limit: limit, Filter: filter})
+	if err != nil {
+		return shortservice.Page{}, err
+	}
+	response := resp.(ListResponse)
+	return response.Page, response.Err
+}
+
+// Find implements the service interface, so Set may be used as a service.
+// This is primarily useful in the context of a client library.
+func (s Set) Find(ctx context.Context, v string) (string, error) {
+	resp, err := s.FindEndpoint(ctx, FindRequest{V: v})
+	if err != nil {
+		return "", err
+	}
+	response := resp.(FindResponse)
+	return response.K, response.Err
+}
+
 // MakeCreateEndpoint constructs a Create endpoint wrapping the service.
 func MakeCreateEndpoint(s shortservice.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(CreateRequest)
-		k, err := s.Create(ctx, req.V)
+		ttl, err := duration(req.TTL)
+		if err != nil {
+			return CreateResponse{Err: err}, nil
+		}
+		k, err := s.Create(ctx, req.V,
+			shortservice.WithTTL(ttl),
+			shortservice.WithOwner(req.Owner),
+			shortservice.WithKey(req.Key),
+		)
 		return CreateResponse{K: k, Err: err}, nil
 	}
 }
@@ -89,15 +392,105 @@ func MakeLookupEndpoint(s shortservice.Service) endpoint.Endpoint {
 	}
 }
 
+// MakeStatEndpoint constructs a Stat endpoint wrapping the service.
+func MakeStatEndpoint(s shortservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(StatRequest)
+		m, err := s.Stat(ctx, req.K)
+		return StatResponse{Metadata: m, Err: err}, nil
+	}
+}
+
+// MakeUpdateEndpoint constructs an Update endpoint wrapping the service.
+func MakeUpdateEndpoint(s shortservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(UpdateRequest)
+		err = s.Update(ctx, req.K, req.V)
+		return UpdateResponse{Err: err}, nil
+	}
+}
+
+// MakeDeleteEndpoint constructs a Delete endpoint wrapping the service.
+func MakeDeleteEndpoint(s shortservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(DeleteRequest)
+		err = s.Delete(ctx, req.K)
+		return DeleteResponse{Err: err}, nil
+	}
+}
+
+// MakeCreateBatchEndpoint constructs a CreateBatch endpoint wrapping the service.
+func MakeCreateBatchEndpoint(s shortservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(CreateBatchRequest)
+		ttl, err := duration(req.TTL)
+		if err != nil {
+			return CreateBatchResponse{Err: err}, nil
+		}
+		results, err := s.CreateBatch(ctx, req.Vs,
+			shortservice.WithTTL(ttl),
+			shortservice.WithOwner(req.Owner),
+		)
+		return CreateBatchResponse{Results: results, Err: err}, nil
+	}
+}
+
+// MakeLookupBatchEndpoint constructs a LookupBatch endpoint wrapping the service.
+func MakeLookupBatchEndpoint(s shortservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(LookupBatchRequest)
+		results, err := s.LookupBatch(ctx, req.Ks)
+		return LookupBatchResponse{Results: results, Err: err}, nil
+	}
+}
+
+// MakeAnalyticsEndpoint constructs an Analytics endpoint wrapping the service.
+func MakeAnalyticsEndpoint(s shortservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(AnalyticsRequest)
+		a, err := s.Analytics(ctx, req.K, req.From, req.To)
+		return AnalyticsResponse{Analytics: a, Err: err}, nil
+	}
+}
+
+// MakeFindEndpoint constructs a Find endpoint wrapping the service.
+func MakeFindEndpoint(s shortservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(FindRequest)
+		k, err := s.Find(ctx, req.V)
+		return FindResponse{K: k, Err: err}, nil
+	}
+}
+
+// MakeListEndpoint constructs a List endpoint wrapping the service.
+func MakeListEndpoint(s shortservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(ListRequest)
+		page, err := s.List(ctx, req.Cursor, req.Limit, req.Filter)
+		return ListResponse{Page: page, Err: err}, nil
+	}
+}
+
 // compile time assertions for our response types implementing endpoint.Failer.
 var (
 	_ endpoint.Failer = CreateResponse{}
 	_ endpoint.Failer = LookupResponse{}
+	_ endpoint.Failer = StatResponse{}
+	_ endpoint.Failer = UpdateResponse{}
+	_ endpoint.Failer = DeleteResponse{}
+	_ endpoint.Failer = CreateBatchResponse{}
+	_ endpoint.Failer = LookupBatchResponse{}
+	_ endpoint.Failer = AnalyticsResponse{}
+	_ endpoint.Failer = ListResponse{}
+	_ endpoint.Failer = FindResponse{}
 )
 
 // CreateRequest collects the request parameters for the Create method.
 type CreateRequest struct {
-	V string
+	V     string
+	TTL   int64  `json:"ttl,omitempty"` // in seconds, zero never expires
+	Owner string `json:"owner,omitempty"`
+	Key   string `json:"key,omitempty"` // custom key, generated if empty
 }
 
 // CreateResponse collects the response values for the Create method.
@@ -122,3 +515,143 @@ type LookupResponse struct {
 
 // Failed implements endpoint.Failer.
 func (r LookupResponse) Failed() error { return r.Err }
+
+// StatRequest collects the request parameters for the Stat method.
+type StatRequest struct {
+	K string
+}
+
+// StatResponse collects the response values for the Stat method.
+type StatResponse struct {
+	shortservice.Metadata
+	Err error `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r StatResponse) Failed() error { return r.Err }
+
+// UpdateRequest collects the request parameters for the Update method.
+type UpdateRequest struct {
+	K string `json:"-"` // taken from the path
+	V string `json:"v"`
+}
+
+// UpdateResponse collects the response values for the Update method.
+type UpdateResponse struct {
+	Err error `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r UpdateResponse) Failed() error { return r.Err }
+
+// DeleteRequest collects the request parameters for the Delete method.
+type DeleteRequest struct {
+	K string
+}
+
+// DeleteResponse collects the response values for the Delete method.
+type DeleteResponse struct {
+	Err error `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r DeleteResponse) Failed() error { return r.Err }
+
+// CreateBatchRequest collects the request parameters for the CreateBatch method.
+type CreateBatchRequest struct {
+	Vs    []string `json:"vs"`
+	TTL   int64    `json:"ttl,omitempty"` // in seconds, zero never expires
+	Owner string   `json:"owner,omitempty"`
+}
+
+// CreateBatchResponse collects the response values for the CreateBatch
+// method. Err fails the whole batch, errors of single values are reported
+// in their results.
+type CreateBatchResponse struct {
+	Results []shortservice.Result `json:"results"`
+	Err     error                 `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r CreateBatchResponse) Failed() error { return r.Err }
+
+// LookupBatchRequest collects the request parameters for the LookupBatch method.
+type LookupBatchRequest struct {
+	Ks []string `json:"ks"`
+}
+
+// LookupBatchResponse collects the response values for the LookupBatch
+// method. Err fails the whole batch, errors of single keys are reported in
+// their results.
+type LookupBatchResponse struct {
+	Results []shortservice.Result `json:"results"`
+	Err     error                 `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r LookupBatchResponse) Failed() error { return r.Err }
+
+// AnalyticsRequest collects the request parameters for the Analytics method.
+type AnalyticsRequest struct {
+	K    string
+	From time.Time // zero from the beginning of the retention period
+	To   time.Time // zero until now
+}
+
+// AnalyticsResponse collects the response values for the Analytics method.
+type AnalyticsResponse struct {
+	shortservice.Analytics
+	Err error `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r AnalyticsResponse) Failed() error { return r.Err }
+
+// ListRequest collects the request parameters for the List method.
+type ListRequest struct {
+	Cursor string // empty from the first entry
+	Limit  int    // zero for the default
+	Filter shortservice.Filter
+}
+
+// ListResponse collects the response values for the List method.
+type ListResponse struct {
+	shortservice.Page
+	Err error `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r ListResponse) Failed() error { return r.Err }
+
+// FindRequest collects the request parameters for the Find method.
+type FindRequest struct {
+	V string
+}
+
+// FindResponse collects the response values for the Find method.
+type FindResponse struct {
+	K   string `json:"k"`
+	Err error  `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r FindResponse) Failed() error { return r.Err }
+
+// Seconds converts the TTL d to the whole seconds of requests, rounding up
+// so that a positive duration never becomes zero. Clients of other
+// transports use it to send TTLs the way Set does.
+func Seconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// maxTTL is the longest TTL in seconds that fits a time.Duration.
+const maxTTL = math.MaxInt64 / int64(time.Second)
+
+// duration converts a TTL in whole seconds to a time.Duration, failing with
+// shortservice.ErrInvalidTTL for TTLs that are negative or don't fit one.
+func duration(ttl int64) (time.Duration, error) {
+	if ttl < 0 || ttl > maxTTL {
+		return 0, shortservice.ErrInvalidTTL
+	}
+	return time.Duration(ttl) * time.Second, nil
+}
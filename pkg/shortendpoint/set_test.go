@@ -0,0 +1,38 @@
+package shortendpoint
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/discard"
+
+	"github.com/sgarcez/short/pkg/shortservice"
+)
+
+func TestCreateTTL(t *testing.T) {
+	svc := shortservice.NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	create, createBatch := MakeCreateEndpoint(svc), MakeCreateBatchEndpoint(svc)
+	ctx := context.Background()
+
+	for _, testcase := range []struct {
+		ttl  int64
+		want error
+	}{
+		{0, nil},
+		{maxTTL, nil},
+		{maxTTL + 1, shortservice.ErrInvalidTTL},
+		{math.MaxInt64, shortservice.ErrInvalidTTL},
+		{-1, shortservice.ErrInvalidTTL},
+	} {
+		resp, _ := create(ctx, CreateRequest{V: "http://a.com", TTL: testcase.ttl})
+		if have := resp.(CreateResponse).Err; have != testcase.want {
+			t.Errorf("Create with ttl %d: want %v, have %v", testcase.ttl, testcase.want, have)
+		}
+		resp, _ = createBatch(ctx, CreateBatchRequest{Vs: []string{"http://b.com"}, TTL: testcase.ttl})
+		if have := resp.(CreateBatchResponse).Err; have != testcase.want {
+			t.Errorf("CreateBatch with ttl %d: want %v, have %v", testcase.ttl, testcase.want, have)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package shortservice
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Alphabet is the set of characters keys are made of.
+type Alphabet struct {
+	name  string
+	chars string
+	// fold maps user input onto the alphabet, for alphabets meant to be
+	// read and typed by people. Nil means keys are used verbatim.
+	fold func(string) string
+	// encode encodes a hash in the alphabet.
+	encode func([]byte) string
+}
+
+var (
+	// Base64URL is the URL safe base64 alphabet. It is the default.
+	Base64URL = newAlphabet("base64url", "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_", nil)
+	// Base62 is the alphanumeric alphabet, without the - and _ of Base64URL.
+	Base62 = newAlphabet("base62", "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", nil)
+	// Crockford32 is Crockford's base32 alphabet, which leaves out I, L, O
+	// and U. Keys are case insensitive and I, L and O are read as 1, 1 and 0.
+	Crockford32 = newAlphabet("crockford32", "0123456789ABCDEFGHJKMNPQRSTVWXYZ", crockfordFold)
+	// Lowercase is made of lowercase letters and digits. Keys are case
+	// insensitive.
+	Lowercase = newAlphabet("lowercase", "0123456789abcdefghijklmnopqrstuvwxyz", strings.ToLower)
+)
+
+// Alphabets lists the supported alphabets.
+var Alphabets = []Alphabet{Base64URL, Base62, Crockford32, Lowercase}
+
+// AlphabetByName returns the supported alphabet with the given name.
+func AlphabetByName(name string) (Alphabet, error) {
+	for _, a := range Alphabets {
+		if a.name == name {
+			return a, nil
+		}
+	}
+	return Alphabet{}, fmt.Errorf("unknown alphabet %q", name)
+}
+
+func newAlphabet(name, chars string, fold func(string) string) Alphabet {
+	a := Alphabet{name: name, chars: chars, fold: fold}
+	switch len(chars) {
+	case 64:
+		a.encode = base64.NewEncoding(chars).WithPadding(base64.NoPadding).EncodeToString
+	case 32:
+		a.encode = base32.NewEncoding(chars).WithPadding(base32.NoPadding).EncodeToString
+	default:
+		a.encode = func(b []byte) string {
+			return a.format(new(big.Int).SetBytes(b), 0)
+		}
+	}
+	return a
+}
+
+// String returns the name of the alphabet.
+func (a Alphabet) String() string { return a.name }
+
+// Normalize maps k onto the alphabet where it has more than one spelling.
+func (a Alphabet) Normalize(k string) string {
+	if a.fold == nil {
+		return k
+	}
+	return a.fold(k)
+}
+
+// Valid reports whether k only uses characters of the alphabet.
+func (a Alphabet) Valid(k string) bool {
+	for _, c := range k {
+		if !strings.ContainsRune(a.chars, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// format encodes n in the alphabet, left padded with its zero character to
+// at least size characters.
+func (a Alphabet) format(n *big.Int, size int) string {
+	var (
+		base = big.NewInt(int64(len(a.chars)))
+		mod  = new(big.Int)
+		b    []byte
+	)
+	for n = new(big.Int).Set(n); n.Sign() > 0; {
+		n.DivMod(n, base, mod)
+		b = append(b, a.chars[mod.Int64()])
+	}
+	for len(b) < size {
+		b = append(b, a.chars[0])
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// parse decodes k, as encoded by format, or reports false if k isn't in the
+// alphabet.
+func (a Alphabet) parse(k string) (*big.Int, bool) {
+	var (
+		base = big.NewInt(int64(len(a.chars)))
+		n    = new(big.Int)
+	)
+	for i := 0; i < len(k); i++ {
+		d := strings.IndexByte(a.chars, k[i])
+		if d < 0 {
+			return nil, false
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(d)))
+	}
+	return n, true
+}
+
+func crockfordFold(k string) string {
+	return strings.NewReplacer("I", "1", "L", "1", "O", "0").Replace(strings.ToUpper(k))
+}
+
+// KeyFormat describes the keys a generator produces.
+type KeyFormat struct {
+	Alphabet Alphabet
+	// MinSize is the length of the shortest keys. Generators use longer
+	// keys once the short ones run out.
+	MinSize int
+}
+
+// DefaultKeyFormat is the format of keys unless configured otherwise.
+var DefaultKeyFormat = KeyFormat{Alphabet: Base64URL, MinSize: 6}
+
+// maxMinKeySize bounds KeyFormat.MinSize so hash based generators, whose
+// hashes are at least 22 characters long, keep a few windows of the
+// shortest size.
+const maxMinKeySize = 16
+
+// Validate reports whether f can be used to generate keys.
+func (f KeyFormat) Validate() error {
+	if f.Alphabet.encode == nil {
+		return fmt.Errorf("missing alphabet")
+	}
+	if f.MinSize < 1 || f.MinSize > maxMinKeySize {
+		return fmt.Errorf("minimum key size must be between 1 and %d", maxMinKeySize)
+	}
+	return nil
+}
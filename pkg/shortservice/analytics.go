@@ -0,0 +1,413 @@
+package shortservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+var (
+	// ErrAnalyticsDisabled is returned by Analytics when lookups aren't
+	// collected.
+	ErrAnalyticsDisabled = errors.New("analytics disabled")
+	// ErrInvalidRange protects the Analytics and List methods from ranges
+	// that end before they start.
+	ErrInvalidRange = errors.New("invalid time range")
+)
+
+// Analytics summarises the lookups of a key over a time range.
+type Analytics struct {
+	// Hourly and Daily count lookups per hour and per day in UTC, oldest
+	// first. Periods without lookups are left out.
+	Hourly []Bucket `json:"hourly"`
+	Daily  []Bucket `json:"daily"`
+	// Referrers counts lookups by the host of their referrer, or
+	// ReferrerDirect and ReferrerOther.
+	Referrers map[string]uint64 `json:"referrers"`
+	// Agents counts lookups by the class of their user agent, one of the
+	// Agent constants.
+	Agents map[string]uint64 `json:"agents"`
+	// Visitors estimates how many distinct visitors looked the key up.
+	Visitors uint64 `json:"visitors"`
+}
+
+// Bucket counts the lookups of a key in the period beginning at Start.
+type Bucket struct {
+	Start   time.Time `json:"start"`
+	Lookups uint64    `json:"lookups"`
+}
+
+// Referrers of lookups that aren't a host.
+const (
+	// ReferrerDirect counts lookups without a referrer.
+	ReferrerDirect = "direct"
+	// ReferrerOther counts lookups with a referrer that isn't a URL, and
+	// lookups from hosts beyond the ones tracked in a day.
+	ReferrerOther = "other"
+)
+
+// Classes of user agents.
+const (
+	AgentBrowser = "browser"
+	AgentMobile  = "mobile"
+	AgentBot     = "bot"
+	AgentTool    = "tool"
+	AgentOther   = "other"
+	AgentUnknown = "unknown"
+)
+
+// maxReferrers is how many referrer hosts are counted per key and day.
+const maxReferrers = 100
+
+// Visit describes the caller of a lookup, as far as the transport can tell.
+type Visit struct {
+	// Visitor identifies the caller, such as by its address. Only a hash of
+	// it and the user agent is kept.
+	Visitor   string
+	Referrer  string
+	UserAgent string
+}
+
+type contextKey int
+
+const visitKey contextKey = iota
+
+// WithVisit returns a copy of ctx carrying v. Transports set it before
+// looking a key up.
+func WithVisit(ctx context.Context, v Visit) context.Context {
+	return context.WithValue(ctx, visitKey, v)
+}
+
+// VisitFromContext returns the visit carried by ctx, if any.
+func VisitFromContext(ctx context.Context) Visit {
+	v, _ := ctx.Value(visitKey).(Visit)
+	return v
+}
+
+// Collector keeps the analytics of looked up keys in memory for a retention
+// period. Lookups are queued and recorded in the background, and dropped
+// when the queue is full, or when they are of a new key and the most keys
+// are already tracked. Every key takes about 1KB a day for its visitors.
+// Keys are forgotten through the same queue, after the lookups before them.
+type Collector struct {
+	retention time.Duration
+	maxKeys   int
+	dropped   metrics.Counter
+	events    chan lookupEvent
+	quit      chan struct{}
+	done      chan struct{}
+
+	mtx  sync.RWMutex
+	keys map[string]*keyAnalytics
+}
+
+type lookupEvent struct {
+	k      string
+	t      time.Time
+	visit  Visit
+	forget bool // forget the analytics of k instead
+}
+
+// keyAnalytics holds the analytics of a key, by Unix hour and Unix day.
+type keyAnalytics struct {
+	hours map[int64]uint64
+	days  map[int64]*dayAnalytics
+}
+
+type dayAnalytics struct {
+	lookups   uint64
+	referrers map[string]uint64
+	agents    map[string]uint64
+	visitors  hyperLogLog
+}
+
+const (
+	hour = int64(time.Hour / time.Second)
+	day  = int64(24 * time.Hour / time.Second)
+)
+
+// NewCollector returns a Collector keeping analytics of at most maxKeys keys
+// for retention, with a queue of buffer lookups. A maxKeys of 0 tracks any
+// number of keys. Dropped lookups are counted in dropped. Close stops it.
+func NewCollector(retention time.Duration, buffer, maxKeys int, dropped metrics.Counter) *Collector {
+	c := &Collector{
+		retention: retention,
+		maxKeys:   maxKeys,
+		dropped:   dropped,
+		events:    make(chan lookupEvent, buffer),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+		keys:      map[string]*keyAnalytics{},
+	}
+	go c.loop()
+	return c
+}
+
+// Close records the queued lookups and stops collecting. Analytics can
+// still be read afterwards.
+func (c *Collector) Close() {
+	close(c.quit)
+	<-c.done
+}
+
+func (c *Collector) loop() {
+	defer close(c.done)
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-c.events:
+			c.handle(e)
+		case t := <-ticker.C:
+			c.prune(t)
+		case <-c.quit:
+			for {
+				select {
+				case e := <-c.events:
+					c.handle(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collect queues a lookup of k at time t without blocking.
+func (c *Collector) collect(k string, t time.Time, visit Visit) {
+	select {
+	case c.events <- lookupEvent{k: k, t: t, visit: visit}:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// handle records or forgets the key of e.
+func (c *Collector) handle(e lookupEvent) {
+	if e.forget {
+		c.mtx.Lock()
+		delete(c.keys, e.k)
+		c.mtx.Unlock()
+		return
+	}
+	c.record(e)
+}
+
+// record adds a lookup to the analytics of its key.
+func (c *Collector) record(e lookupEvent) {
+	sum := sha256.Sum256([]byte(e.visit.Visitor + "\x00" + e.visit.UserAgent))
+	visitor := binary.BigEndian.Uint64(sum[:8])
+	referrer := referrerHost(e.visit.Referrer)
+	agent := agentClass(e.visit.UserAgent)
+	unix := e.t.Unix()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	a, ok := c.keys[e.k]
+	if !ok {
+		if c.maxKeys > 0 && len(c.keys) >= c.maxKeys {
+			c.dropped.Add(1)
+			return
+		}
+		a = &keyAnalytics{hours: map[int64]uint64{}, days: map[int64]*dayAnalytics{}}
+		c.keys[e.k] = a
+	}
+	a.hours[unix/hour]++
+	d, ok := a.days[unix/day]
+	if !ok {
+		d = &dayAnalytics{referrers: map[string]uint64{}, agents: map[string]uint64{}}
+		a.days[unix/day] = d
+	}
+	d.lookups++
+	if _, ok := d.referrers[referrer]; !ok && len(d.referrers) >= maxReferrers {
+		referrer = ReferrerOther
+	}
+	d.referrers[referrer]++
+	d.agents[agent]++
+	d.visitors.add(visitor)
+}
+
+// forget queues the removal of the analytics of k, so that the lookups
+// queued before it are forgotten too. Unlike lookups it is never dropped,
+// and waits for room in the queue.
+func (c *Collector) forget(k string) {
+	e := lookupEvent{k: k, forget: true}
+	select {
+	case <-c.done:
+		c.handle(e) // nothing reads the queue once closed
+		return
+	default:
+	}
+	select {
+	case c.events <- e:
+	case <-c.done:
+		c.handle(e)
+	}
+}
+
+// prune removes the analytics of periods that ended before the retention
+// period at time t, and keys left without any.
+func (c *Collector) prune(t time.Time) {
+	oldest := t.Add(-c.retention).Unix()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for k, a := range c.keys {
+		for h := range a.hours {
+			if (h+1)*hour <= oldest {
+				delete(a.hours, h)
+			}
+		}
+		for d := range a.days {
+			if (d+1)*day <= oldest {
+				delete(a.days, d)
+			}
+		}
+		if len(a.hours) == 0 && len(a.days) == 0 {
+			delete(c.keys, k)
+		}
+	}
+}
+
+// Analytics returns the analytics of k for the hours and days overlapping
+// [from, to). Referrers, agents and visitors are counted by whole days.
+func (c *Collector) Analytics(k string, from, to time.Time) Analytics {
+	result := Analytics{
+		Hourly:    []Bucket{},
+		Daily:     []Bucket{},
+		Referrers: map[string]uint64{},
+		Agents:    map[string]uint64{},
+	}
+	start, end := from.Unix(), to.Unix()
+
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	a, ok := c.keys[k]
+	if !ok {
+		return result
+	}
+	for h, n := range a.hours {
+		if (h+1)*hour > start && h*hour < end {
+			result.Hourly = append(result.Hourly, Bucket{Start: time.Unix(h*hour, 0).UTC(), Lookups: n})
+		}
+	}
+	var visitors hyperLogLog
+	for d, s := range a.days {
+		if (d+1)*day <= start || d*day >= end {
+			continue
+		}
+		result.Daily = append(result.Daily, Bucket{Start: time.Unix(d*day, 0).UTC(), Lookups: s.lookups})
+		for r, n := range s.referrers {
+			result.Referrers[r] += n
+		}
+		for ua, n := range s.agents {
+			result.Agents[ua] += n
+		}
+		visitors.merge(&s.visitors)
+	}
+	result.Visitors = visitors.estimate()
+
+	sortBuckets(result.Hourly)
+	sortBuckets(result.Daily)
+	return result
+}
+
+func sortBuckets(b []Bucket) {
+	sort.Slice(b, func(i, j int) bool { return b[i].Start.Before(b[j].Start) })
+}
+
+// referrerHost returns the host of the referrer URL ref.
+func referrerHost(ref string) string {
+	if ref == "" {
+		return ReferrerDirect
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Hostname() == "" {
+		return ReferrerOther
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// agentClass classifies the user agent ua.
+func agentClass(ua string) string {
+	ua = strings.ToLower(ua)
+	switch {
+	case ua == "":
+		return AgentUnknown
+	case containsAny(ua, "bot", "crawl", "spider", "slurp", "preview", "facebookexternalhit"):
+		return AgentBot
+	case containsAny(ua, "curl", "wget", "python", "go-http-client", "grpc-", "java/", "okhttp", "httpie"):
+		return AgentTool
+	case containsAny(ua, "mobi", "android", "iphone", "ipad"):
+		return AgentMobile
+	case strings.HasPrefix(ua, "mozilla/") || strings.HasPrefix(ua, "opera"):
+		return AgentBrowser
+	}
+	return AgentOther
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyticsMiddleware returns a service middleware that hands successful
+// lookups to c, along with the Visit in their context, without waiting for
+// them to be recorded. Keys are normalized to alphabet first. Lookups in a
+// batch are collected one by one. The analytics of deleted keys are
+// forgotten.
+func AnalyticsMiddleware(c *Collector, alphabet Alphabet) Middleware {
+	return func(next Service) Service {
+		return analyticsMiddleware{c, alphabet, next}
+	}
+}
+
+type analyticsMiddleware struct {
+	collector *Collector
+	alphabet  Alphabet
+	Service
+}
+
+func (mw analyticsMiddleware) Lookup(ctx context.Context, k string) (string, error) {
+	v, err := mw.Service.Lookup(ctx, k)
+	if err == nil {
+		mw.collector.collect(mw.alphabet.Normalize(k), time.Now(), VisitFromContext(ctx))
+	}
+	return v, err
+}
+
+func (mw analyticsMiddleware) LookupBatch(ctx context.Context, ks []string) ([]Result, error) {
+	results, err := mw.Service.LookupBatch(ctx, ks)
+	if err == nil {
+		now, visit := time.Now(), VisitFromContext(ctx)
+		for _, r := range results {
+			if r.Err == nil {
+				mw.collector.collect(mw.alphabet.Normalize(r.K), now, visit)
+			}
+		}
+	}
+	return results, err
+}
+
+func (mw analyticsMiddleware) Delete(ctx context.Context, k string) error {
+	err := mw.Service.Delete(ctx, k)
+	if err == nil {
+		mw.collector.forget(mw.alphabet.Normalize(k))
+	}
+	return err
+}
@@ -0,0 +1,226 @@
+package shortservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector(48*time.Hour, 10, 0, discard.NewCounter())
+	defer c.Close()
+
+	day := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, e := range []lookupEvent{
+		{k: "k", t: day.Add(10 * time.Minute), visit: Visit{Visitor: "1.1.1.1", Referrer: "https://News.example.com/a", UserAgent: "Mozilla/5.0 (X11; Linux x86_64)"}},
+		{k: "k", t: day.Add(20 * time.Minute), visit: Visit{Visitor: "1.1.1.1", Referrer: "https://news.example.com/b", UserAgent: "Mozilla/5.0 (X11; Linux x86_64)"}},
+		{k: "k", t: day.Add(90 * time.Minute), visit: Visit{Visitor: "2.2.2.2", UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 12_0) Mobile/15E148"}},
+		{k: "k", t: day.Add(25 * time.Hour), visit: Visit{Visitor: "3.3.3.3", Referrer: "not a url", UserAgent: "curl/7.54.0"}},
+		{k: "other", t: day, visit: Visit{}},
+	} {
+		c.record(e)
+	}
+
+	have := c.Analytics("k", day, day.Add(48*time.Hour))
+	want := Analytics{
+		Hourly: []Bucket{
+			{day, 2},
+			{day.Add(time.Hour), 1},
+			{day.Add(25 * time.Hour), 1},
+		},
+		Daily: []Bucket{
+			{day, 3},
+			{day.Add(24 * time.Hour), 1},
+		},
+		Referrers: map[string]uint64{"news.example.com": 2, ReferrerDirect: 1, ReferrerOther: 1},
+		Agents:    map[string]uint64{AgentBrowser: 2, AgentMobile: 1, AgentTool: 1},
+		Visitors:  3,
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("Analytics: want %+v, have %+v", want, have)
+	}
+
+	// Hours are picked by the range, the rest by whole days.
+	have = c.Analytics("k", day.Add(time.Hour), day.Add(2*time.Hour))
+	if want := []Bucket{{day.Add(time.Hour), 1}}; !reflect.DeepEqual(want, have.Hourly) {
+		t.Errorf("Analytics of one hour: want hourly %v, have %v", want, have.Hourly)
+	}
+	if have.Visitors != 2 || len(have.Daily) != 1 {
+		t.Errorf("Analytics of one hour: want 2 visitors on one day, have %d on %v", have.Visitors, have.Daily)
+	}
+
+	c.prune(day.Add(73 * time.Hour))
+	have = c.Analytics("k", time.Time{}, day.Add(72*time.Hour))
+	if want := []Bucket{{day.Add(24 * time.Hour), 1}}; !reflect.DeepEqual(want, have.Daily) {
+		t.Errorf("Analytics after prune: want daily %v, have %v", want, have.Daily)
+	}
+	if have := c.Analytics("other", time.Time{}, day.Add(72*time.Hour)); len(have.Daily) != 0 {
+		t.Errorf("Analytics of pruned key: want none, have %v", have.Daily)
+	}
+}
+
+func TestCollectorDrops(t *testing.T) {
+	dropped := generic.NewCounter("dropped")
+	c := &Collector{dropped: dropped, events: make(chan lookupEvent, 1)}
+	c.collect("k", time.Now(), Visit{})
+	c.collect("k", time.Now(), Visit{})
+	if want, have := 1.0, dropped.Value(); want != have {
+		t.Errorf("dropped: want %v, have %v", want, have)
+	}
+}
+
+func TestCollectorMaxKeys(t *testing.T) {
+	dropped := generic.NewCounter("dropped")
+	c := &Collector{maxKeys: 1, dropped: dropped, keys: map[string]*keyAnalytics{}}
+	now := time.Now()
+	for _, k := range []string{"a", "b", "a"} {
+		c.record(lookupEvent{k: k, t: now})
+	}
+	if want, have := 1.0, dropped.Value(); want != have {
+		t.Errorf("dropped: want %v, have %v", want, have)
+	}
+	if want, have := uint64(2), c.Analytics("a", now.Add(-time.Hour), now.Add(time.Hour)).Daily; len(have) != 1 || have[0].Lookups != want {
+		t.Errorf("Analytics of a: want %d lookups, have %v", want, have)
+	}
+	if have := c.Analytics("b", now.Add(-time.Hour), now.Add(time.Hour)).Daily; len(have) != 0 {
+		t.Errorf("Analytics of b: want none, have %v", have)
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		var h hyperLogLog
+		for i := 0; i < n; i++ {
+			var b [8]byte
+			binary.BigEndian.PutUint64(b[:], uint64(i))
+			sum := sha256.Sum256(b[:])
+			h.add(binary.BigEndian.Uint64(sum[:8]))
+		}
+		// Allow for three standard errors, and a collision among few hashes.
+		have := float64(h.estimate())
+		if math.Abs(have-float64(n)) > 1+0.1*float64(n) {
+			t.Errorf("estimate of %d: have %v", n, have)
+		}
+	}
+}
+
+func TestAgentClass(t *testing.T) {
+	for ua, want := range map[string]string{
+		"": AgentUnknown,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/72.0":        AgentBrowser,
+		"Mozilla/5.0 (Linux; Android 9; Pixel 3) AppleWebKit/537.36 Mobile Safari/537.36": AgentMobile,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":        AgentBot,
+		"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)":                      AgentBot,
+		"grpc-go/1.19.0":     AgentTool,
+		"Go-http-client/1.1": AgentTool,
+		"Something/1.0":      AgentOther,
+	} {
+		if have := agentClass(ua); want != have {
+			t.Errorf("agentClass(%q): want %q, have %q", ua, want, have)
+		}
+	}
+}
+
+func TestServiceAnalytics(t *testing.T) {
+	ctx := context.Background()
+	svc := NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	if _, err := svc.Analytics(ctx, "gnzLDu", time.Time{}, time.Time{}); err != ErrAnalyticsDisabled {
+		t.Errorf("Analytics without a collector: want %v, have %v", ErrAnalyticsDisabled, err)
+	}
+
+	c := NewCollector(time.Hour, 10, 0, discard.NewCounter())
+	svc = NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(), WithAnalytics(c))
+	k, err := svc.Create(ctx, "http://a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, visitor := range []string{"1.1.1.1", "2.2.2.2", "1.1.1.1"} {
+		if _, err := svc.Lookup(WithVisit(ctx, Visit{Visitor: visitor}), k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := svc.Lookup(ctx, "nope"); err != ErrKeyNotFound {
+		t.Fatalf("Lookup: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if _, err := svc.LookupBatch(WithVisit(ctx, Visit{Visitor: "3.3.3.3"}), []string{k, "nope"}); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	a, err := svc.Analytics(ctx, k, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Daily) != 1 || a.Daily[0].Lookups != 4 || a.Visitors != 3 {
+		t.Errorf("Analytics: want 4 lookups by 3 visitors, have %v by %d", a.Daily, a.Visitors)
+	}
+	if _, err := svc.Analytics(ctx, "nope", time.Time{}, time.Time{}); err != ErrKeyNotFound {
+		t.Errorf("Analytics of a missing key: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if _, err := svc.Analytics(ctx, k, time.Now(), time.Now().Add(-time.Hour)); err != ErrInvalidRange {
+		t.Errorf("Analytics of a reversed range: want %v, have %v", ErrInvalidRange, err)
+	}
+
+	if err := svc.Delete(ctx, k); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, "http://a.com"); err != nil {
+		t.Fatal(err)
+	}
+	if a, err := svc.Analytics(ctx, k, time.Time{}, time.Time{}); err != nil || len(a.Daily) != 0 {
+		t.Errorf("Analytics of a recreated key: want none, have %v (%v)", a.Daily, err)
+	}
+}
+
+func TestServiceAnalyticsReissued(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector(time.Hour, 10, 0, discard.NewCounter())
+	store := NewInMemStore()
+	svc := NewService(store, log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(), WithAnalytics(c))
+
+	// A custom key replacing an expired entry, and a generated key reissued
+	// after its entry was reaped, each looked up once before and after.
+	var keys []string
+	for _, custom := range []bool{true, false} {
+		opts := []CreateOption{WithTTL(50 * time.Millisecond)}
+		if custom {
+			opts = append(opts, WithKey("spring-sale"))
+		}
+		k, err := svc.Create(ctx, "http://a.com", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.Lookup(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(60 * time.Millisecond)
+		if !custom {
+			if _, err := store.Reap(time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if again, err := svc.Create(ctx, "http://a.com", opts[1:]...); again != k || err != nil {
+			t.Fatalf("Create of an expired key: want %q, have %q (%v)", k, again, err)
+		}
+		if _, err := svc.Lookup(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, k)
+	}
+	c.Close()
+
+	for _, k := range keys {
+		a := c.Analytics(k, time.Time{}, time.Now().Add(time.Hour))
+		if len(a.Daily) != 1 || a.Daily[0].Lookups != 1 {
+			t.Errorf("Analytics of reissued key %q: want 1 lookup, have %v", k, a.Daily)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package shortservice
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// Blocklist holds words that keys must not contain, such as offensive words
+// or names reserved for routes. Matching is case insensitive and a word
+// blocks every key that contains it.
+type Blocklist struct {
+	file  string
+	mtx   sync.RWMutex
+	words []string
+}
+
+// NewBlocklist returns a Blocklist of the given words.
+func NewBlocklist(words ...string) *Blocklist {
+	b := &Blocklist{}
+	b.SetWords(words...)
+	return b
+}
+
+// LoadBlocklist returns a Blocklist of the words in file, one per line.
+// Blank lines and lines starting with # are ignored. Reload reads the file
+// again.
+func LoadBlocklist(file string) (*Blocklist, error) {
+	b := &Blocklist{file: file}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Reload replaces the words with the contents of the file the Blocklist was
+// loaded from. On error the current words are kept. It does nothing for
+// blocklists that weren't loaded from a file.
+func (b *Blocklist) Reload() error {
+	if b.file == "" {
+		return nil
+	}
+	buf, err := ioutil.ReadFile(b.file)
+	if err != nil {
+		return err
+	}
+
+	var words []string
+	for _, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	b.SetWords(words...)
+	return nil
+}
+
+// SetWords replaces the words of the blocklist.
+func (b *Blocklist) SetWords(words ...string) {
+	lower := make([]string, 0, len(words))
+	for _, w := range words {
+		if w != "" {
+			lower = append(lower, strings.ToLower(w))
+		}
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.words = lower
+}
+
+// Len returns the number of words in the blocklist.
+func (b *Blocklist) Len() int {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	return len(b.words)
+}
+
+// Blocked reports whether k contains any of the words.
+func (b *Blocklist) Blocked(k string) bool {
+	k = strings.ToLower(k)
+
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	for _, w := range b.words {
+		if strings.Contains(k, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package shortservice
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestBlocklist(t *testing.T) {
+	blocked := generic.NewCounter("blocked")
+	svc := NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(),
+		WithBlocklist(NewBlocklist("GNZ", "admin"), blocked))
+	ctx := context.Background()
+
+	// The first window of 12345 is gnzLDu.
+	if k, err := svc.Create(ctx, "12345"); k != "nzLDuq" || err != nil {
+		t.Errorf("Create: want nzLDuq, have %q (%v)", k, err)
+	}
+	if want, have := 1.0, blocked.Value(); want != have {
+		t.Errorf("blocked: want %v, have %v", want, have)
+	}
+	if _, err := svc.Create(ctx, "http://a.com", WithKey("my-Admin")); err != ErrInvalidKey {
+		t.Errorf("Create with blocked custom key: want %v, have %v", ErrInvalidKey, err)
+	}
+
+	// Skipped keys needn't be counted.
+	svc = NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(),
+		WithBlocklist(NewBlocklist("GNZ"), nil))
+	if k, err := svc.Create(ctx, "12345"); k != "nzLDuq" || err != nil {
+		t.Errorf("Create with no counter: want nzLDuq, have %q (%v)", k, err)
+	}
+}
+
+func TestBlocklistReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blocklist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "words.txt")
+
+	if err := ioutil.WriteFile(file, []byte("# reserved\napi\n\nhealth\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBlocklist(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, b.Len(); want != have {
+		t.Errorf("Len: want %d, have %d", want, have)
+	}
+	if !b.Blocked("xAPIx") || b.Blocked("admin") {
+		t.Errorf("want api blocked and admin allowed")
+	}
+
+	if err := ioutil.WriteFile(file, []byte("admin\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if b.Blocked("xAPIx") || !b.Blocked("admin") {
+		t.Errorf("after reload: want api allowed and admin blocked")
+	}
+
+	os.Remove(file)
+	if err := b.Reload(); err == nil || !b.Blocked("admin") {
+		t.Errorf("failed reload: want error and previous words kept, have %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package shortservice
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CanonicalSteps selects how a Canonicalizer rewrites URL values, so that
+// equivalent URLs are stored, and counted, under a single key.
+type CanonicalSteps int
+
+const (
+	// CanonicalCase lowercases the scheme and host.
+	CanonicalCase CanonicalSteps = 1 << iota
+	// CanonicalPort removes the default port of the scheme.
+	CanonicalPort
+	// CanonicalPath resolves "." and ".." segments in the path, and turns
+	// an empty path into "/".
+	CanonicalPath
+	// CanonicalQuery sorts query parameters by name, keeping the order of
+	// repeated ones.
+	CanonicalQuery
+	// CanonicalTracking removes tracking query parameters.
+	CanonicalTracking
+
+	// CanonicalAll selects every step.
+	CanonicalAll = CanonicalCase | CanonicalPort | CanonicalPath | CanonicalQuery | CanonicalTracking
+)
+
+var canonicalStepNames = []struct {
+	step CanonicalSteps
+	name string
+}{
+	{CanonicalCase, "case"},
+	{CanonicalPort, "port"},
+	{CanonicalPath, "path"},
+	{CanonicalQuery, "query"},
+	{CanonicalTracking, "tracking"},
+}
+
+// ParseCanonicalSteps returns the steps in a comma separated list of names:
+// case, port, path, query, tracking, or all of them. An empty list selects
+// none.
+func ParseCanonicalSteps(names string) (CanonicalSteps, error) {
+	var steps CanonicalSteps
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			steps |= CanonicalAll
+			continue
+		}
+		step, ok := CanonicalSteps(0), false
+		for _, s := range canonicalStepNames {
+			if s.name == name {
+				step, ok = s.step, true
+			}
+		}
+		if !ok {
+			return 0, fmt.Errorf("unknown canonicalization step %q", name)
+		}
+		steps |= step
+	}
+	return steps, nil
+}
+
+// String returns the comma separated names of the steps.
+func (steps CanonicalSteps) String() string {
+	var names []string
+	for _, s := range canonicalStepNames {
+		if steps&s.step != 0 {
+			names = append(names, s.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// DefaultTrackingParams are the query parameters removed by the tracking
+// step when no others are given.
+var DefaultTrackingParams = []string{"utm_*", "fbclid", "gclid"}
+
+// Canonicalizer rewrites absolute HTTP and HTTPS URLs into a canonical form
+// before they are stored. Other values are left alone. Parts of the URL the
+// steps don't touch keep their original escaping.
+type Canonicalizer struct {
+	steps    CanonicalSteps
+	tracking []string
+}
+
+// NewCanonicalizer returns a Canonicalizer applying steps. The tracking step
+// removes the query parameters named in tracking, case insensitively, where a
+// trailing "*" matches any suffix. It defaults to DefaultTrackingParams.
+func NewCanonicalizer(steps CanonicalSteps, tracking ...string) *Canonicalizer {
+	if len(tracking) == 0 {
+		tracking = DefaultTrackingParams
+	}
+	c := &Canonicalizer{steps: steps}
+	for _, p := range tracking {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			c.tracking = append(c.tracking, p)
+		}
+	}
+	return c
+}
+
+// Canonicalize returns the canonical form of v.
+func (c *Canonicalizer) Canonicalize(v string) string {
+	if c.steps == 0 {
+		return v
+	}
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Opaque != "" {
+		return v
+	}
+
+	// Split v itself rather than reassembling u, which would escape parts
+	// of it anew.
+	i := strings.Index(v, "://")
+	if i < 0 {
+		return v
+	}
+	scheme, rest := v[:i], v[i+3:]
+	var fragment string
+	if j := strings.IndexByte(rest, '#'); j >= 0 {
+		rest, fragment = rest[:j], rest[j:]
+	}
+	var query string
+	hasQuery := false
+	if j := strings.IndexByte(rest, '?'); j >= 0 {
+		rest, query, hasQuery = rest[:j], rest[j+1:], true
+	}
+	authority, path := rest, ""
+	if j := strings.IndexByte(rest, '/'); j >= 0 {
+		authority, path = rest[:j], rest[j:]
+	}
+	var userinfo string
+	host := authority
+	if j := strings.LastIndexByte(authority, '@'); j >= 0 {
+		userinfo, host = authority[:j+1], authority[j+1:]
+	}
+
+	if c.steps&CanonicalCase != 0 {
+		scheme, host = strings.ToLower(scheme), strings.ToLower(host)
+	}
+	if c.steps&CanonicalPort != 0 {
+		host = strings.TrimSuffix(host, ":")
+		if port := map[string]string{"http": ":80", "https": ":443"}[u.Scheme]; strings.HasSuffix(host, port) {
+			host = strings.TrimSuffix(host, port)
+		}
+	}
+	if c.steps&CanonicalPath != 0 {
+		path = removeDotSegments(path)
+	}
+	if c.steps&(CanonicalQuery|CanonicalTracking) != 0 {
+		query = c.canonicalQuery(query)
+		hasQuery = query != ""
+	}
+
+	canonical := scheme + "://" + userinfo + host + path
+	if hasQuery {
+		canonical += "?" + query
+	}
+	return canonical + fragment
+}
+
+// canonicalQuery removes tracking parameters from the raw query q and sorts
+// the rest, as selected by the steps of c.
+func (c *Canonicalizer) canonicalQuery(q string) string {
+	type param struct{ name, raw string }
+	var params []param
+	for _, raw := range strings.Split(q, "&") {
+		if raw == "" {
+			continue
+		}
+		name := raw
+		if j := strings.IndexByte(raw, '='); j >= 0 {
+			name = raw[:j]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if c.steps&CanonicalTracking != 0 && c.isTracking(name) {
+			continue
+		}
+		params = append(params, param{name, raw})
+	}
+	if c.steps&CanonicalQuery != 0 {
+		sort.SliceStable(params, func(i, j int) bool { return params[i].name < params[j].name })
+	}
+
+	raws := make([]string, len(params))
+	for i, p := range params {
+		raws[i] = p.raw
+	}
+	return strings.Join(raws, "&")
+}
+
+func (c *Canonicalizer) isTracking(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range c.tracking {
+		if prefix := strings.TrimSuffix(p, "*"); (prefix != p && strings.HasPrefix(name, prefix)) || name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDotSegments resolves the "." and ".." segments of the absolute path
+// p, as described in RFC 3986 section 5.2.4. An empty path becomes "/".
+func removeDotSegments(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segments := strings.Split(p[1:], "/")
+	out := make([]string, 0, len(segments))
+	for i, s := range segments {
+		last := i == len(segments)-1
+		switch s {
+		case ".":
+		case "..":
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, s)
+			continue
+		}
+		// A path ending in a dot segment names a directory.
+		if last {
+			out = append(out, "")
+		}
+	}
+	return "/" + strings.Join(out, "/")
+}
@@ -0,0 +1,534 @@
+package shortservice
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	walPattern   = "wal-%08d.log"
+)
+
+// FileStore is a Store that keeps its data in memory and makes it durable by
+// appending every write to a log on disk. The log is replayed on startup and
+// periodically compacted into a snapshot in the background.
+//
+// Writes reach the operating system before they are acknowledged, but the
+// log is only synced to disk when a segment is rotated and on Close. A crash
+// of the process loses no acknowledged write, while a crash of the machine
+// may lose the most recent ones.
+//
+// Accesses are not logged, so access metadata is only persisted by
+// compaction and a crash may lose the most recent lookups.
+type FileStore struct {
+	dir    string
+	logger log.Logger
+
+	compactMtx sync.Mutex // serializes compactions
+
+	mtx     sync.RWMutex
+	m       inMemMap
+	seq     uint64   // sequence number of the active log segment
+	wal     *os.File // active log segment
+	broken  bool     // whether a failed write may have left a partial record in wal
+	pending int      // records appended since the last compaction
+	touched bool     // whether any entry was accessed since the last compaction
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// record is a single log entry.
+type record struct {
+	Op string `json:"op"`
+	K  string `json:"k"`
+	Entry
+}
+
+const (
+	opPut    = "put"
+	opDelete = "del"
+)
+
+// snapshot holds the full state of the store up to the start of log
+// segment Seq.
+type snapshot struct {
+	Seq     uint64           `json:"seq"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// NewFileStore opens or creates a FileStore in dir, replaying any existing
+// snapshot and log segments. A background compaction runs every
+// compactEvery; a zero value disables it.
+func NewFileStore(dir string, compactEvery time.Duration, logger log.Logger) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{
+		dir:    dir,
+		logger: logger,
+		m:      newInMemMap(),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	wal, err := s.openSegment(s.seq)
+	if err != nil {
+		return nil, err
+	}
+	s.wal = wal
+
+	go s.compactLoop(compactEvery)
+	return s, nil
+}
+
+// Get implements Store.
+func (s *FileStore) Get(k string) (Entry, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return fileStoreTx{s}.Get(k)
+}
+
+// PutIfAbsent implements Store. The write is appended to the log before it
+// becomes visible to readers.
+func (s *FileStore) PutIfAbsent(k string, e Entry) (Entry, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return fileStoreTx{s}.PutIfAbsent(k, e)
+}
+
+// Touch implements Store.
+func (s *FileStore) Touch(k string, t time.Time) (Entry, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return fileStoreTx{s}.Touch(k, t)
+}
+
+// Update implements Store.
+func (s *FileStore) Update(k string, fn func(e *Entry) error) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return fileStoreTx{s}.Update(k, fn)
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(k string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return fileStoreTx{s}.Delete(k)
+}
+
+// Reap implements Store.
+func (s *FileStore) Reap(t time.Time) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return fileStoreTx{s}.Reap(t)
+}
+
+// Len implements Store.
+func (s *FileStore) Len() (int, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return fileStoreTx{s}.Len()
+}
+
+// Range implements Store.
+func (s *FileStore) Range(after string, fn func(k string, e Entry) bool) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return fileStoreTx{s}.Range(after, fn)
+}
+
+// Find implements Store.
+func (s *FileStore) Find(v string) ([]Item, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return fileStoreTx{s}.Find(v)
+}
+
+// Batch implements Store.
+func (s *FileStore) Batch(fn func(tx Store) error) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return fn(fileStoreTx{s})
+}
+
+// fileStoreTx implements Store on a FileStore whose lock is already held by
+// the caller.
+type fileStoreTx struct {
+	s *FileStore
+}
+
+// Get implements Store.
+func (tx fileStoreTx) Get(k string) (Entry, error) {
+	return tx.s.m.Get(k)
+}
+
+// PutIfAbsent implements Store.
+func (tx fileStoreTx) PutIfAbsent(k string, e Entry) (Entry, bool, error) {
+	if old, exists := tx.s.m.entries[k]; exists {
+		return old, false, nil
+	}
+	if err := tx.s.append(record{Op: opPut, K: k, Entry: e}); err != nil {
+		return Entry{}, false, err
+	}
+	tx.s.m.put(k, e)
+	return e, true, nil
+}
+
+// Touch implements Store.
+func (tx fileStoreTx) Touch(k string, t time.Time) (Entry, error) {
+	e, err := tx.s.m.Touch(k, t)
+	if err != nil {
+		return Entry{}, err
+	}
+	tx.s.touched = true
+	return e, nil
+}
+
+// Update implements Store.
+func (tx fileStoreTx) Update(k string, fn func(e *Entry) error) error {
+	e, ok := tx.s.m.entries[k]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	if err := fn(&e); err != nil {
+		return err
+	}
+	if err := tx.s.append(record{Op: opPut, K: k, Entry: e}); err != nil {
+		return err
+	}
+	tx.s.m.put(k, e)
+	return nil
+}
+
+// Delete implements Store.
+func (tx fileStoreTx) Delete(k string) error {
+	if _, ok := tx.s.m.entries[k]; !ok {
+		return ErrKeyNotFound
+	}
+	if err := tx.s.append(record{Op: opDelete, K: k}); err != nil {
+		return err
+	}
+	tx.s.m.remove(k)
+	return nil
+}
+
+// Reap implements Store.
+func (tx fileStoreTx) Reap(t time.Time) (int, error) {
+	var n int
+	for k, e := range tx.s.m.entries {
+		if !e.Expired(t) {
+			continue
+		}
+		if err := tx.s.append(record{Op: opDelete, K: k}); err != nil {
+			return n, err
+		}
+		tx.s.m.remove(k)
+		n++
+	}
+	return n, nil
+}
+
+// Len implements Store.
+func (tx fileStoreTx) Len() (int, error) {
+	return tx.s.m.Len()
+}
+
+// Range implements Store.
+func (tx fileStoreTx) Range(after string, fn func(k string, e Entry) bool) error {
+	return tx.s.m.Range(after, fn)
+}
+
+// Find implements Store.
+func (tx fileStoreTx) Find(v string) ([]Item, error) {
+	return tx.s.m.Find(v)
+}
+
+// Batch implements Store. The lock is already held by the enclosing batch.
+func (tx fileStoreTx) Batch(fn func(tx Store) error) error {
+	return fn(tx)
+}
+
+// Close stops background compaction and closes the active log segment.
+func (s *FileStore) Close() error {
+	close(s.quit)
+	<-s.done
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.wal.Sync(); err != nil {
+		return err
+	}
+	return s.wal.Close()
+}
+
+// Compact writes a snapshot of the current state and removes the log
+// segments it supersedes. Writes are only blocked while the active
+// segment is rotated, not while the snapshot is written.
+func (s *FileStore) Compact() error {
+	s.compactMtx.Lock()
+	defer s.compactMtx.Unlock()
+
+	s.mtx.Lock()
+	if s.pending == 0 && !s.touched {
+		s.mtx.Unlock()
+		return nil
+	}
+	// Sync before opening the next segment, so a failure leaves nothing
+	// open behind.
+	if err := s.wal.Sync(); err != nil && !s.broken {
+		s.mtx.Unlock()
+		return err
+	}
+	if err := s.rotate(); err != nil {
+		s.mtx.Unlock()
+		return err
+	}
+	s.pending = 0
+	s.touched = false
+
+	snap := snapshot{Seq: s.seq, Entries: make(map[string]Entry, len(s.m.entries))}
+	for k, e := range s.m.entries {
+		snap.Entries[k] = e
+	}
+	s.mtx.Unlock()
+
+	if err := s.writeSnapshot(snap); err != nil {
+		return err
+	}
+	return s.removeSegmentsBefore(snap.Seq)
+}
+
+func (s *FileStore) compactLoop(every time.Duration) {
+	defer close(s.done)
+	if every <= 0 {
+		<-s.quit
+		return
+	}
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Compact(); err != nil {
+				s.logger.Log("during", "compact", "err", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// append writes r to the active log segment. Callers must hold the write lock.
+func (s *FileStore) append(r record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if s.broken {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("failed to append to log: %v", err)
+		}
+	}
+	if _, err := s.wal.Write(append(b, '\n')); err != nil {
+		// A failed write, such as on a full disk, may leave a partial
+		// record. Replay only skips one at the end of a segment, so later
+		// records go to a new segment.
+		s.broken = true
+		if rerr := s.rotate(); rerr != nil {
+			s.logger.Log("during", "append", "segment", s.seq, "err", rerr)
+		}
+		return fmt.Errorf("failed to append to log: %v", err)
+	}
+	s.pending++
+	return nil
+}
+
+// rotate moves appends on to a new log segment after a failed write. Callers
+// must hold the write lock.
+func (s *FileStore) rotate() error {
+	wal, err := s.openSegment(s.seq + 1)
+	if err != nil {
+		return err
+	}
+	s.wal.Close()
+	s.wal = wal
+	s.seq++
+	s.broken = false
+	return nil
+}
+
+// apply replays a single log record onto the in memory state.
+func (s *FileStore) apply(r record) error {
+	switch r.Op {
+	case opPut:
+		s.m.put(r.K, r.Entry)
+	case opDelete:
+		s.m.remove(r.K)
+	default:
+		return fmt.Errorf("unknown log op %q", r.Op)
+	}
+	return nil
+}
+
+// replay loads the latest snapshot, if any, and applies every log segment
+// written after it.
+func (s *FileStore) replay() error {
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, snapshotFile))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		var snap snapshot
+		if err := json.Unmarshal(b, &snap); err != nil {
+			return fmt.Errorf("failed to read snapshot: %v", err)
+		}
+		for k, e := range snap.Entries {
+			s.m.put(k, e)
+		}
+		s.seq = snap.Seq
+	}
+
+	seqs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		if seq < s.seq {
+			continue
+		}
+		if err := s.replaySegment(seq); err != nil {
+			return err
+		}
+		s.seq = seq
+	}
+	// Never append to a replayed segment, it may end in a partial record.
+	if len(seqs) > 0 {
+		s.seq++
+	}
+	s.logger.Log("during", "replay", "dir", s.dir, "keys", len(s.m.entries))
+	return nil
+}
+
+func (s *FileStore) replaySegment(seq uint64) error {
+	f, err := os.Open(s.segmentPath(seq))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// A crash mid-write leaves a partial trailing record, which
+				// was never acknowledged to the caller.
+				s.logger.Log("during", "replay", "segment", seq, "err", "discarding partial record")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("corrupt log segment %d: %v", seq, err)
+		}
+		if err := s.apply(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// segments returns the sequence numbers of all log segments in ascending order.
+func (s *FileStore) segments() ([]uint64, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []uint64
+	for _, fi := range files {
+		var seq uint64
+		if _, err := fmt.Sscanf(fi.Name(), walPattern, &seq); err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+func (s *FileStore) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf(walPattern, seq))
+}
+
+func (s *FileStore) openSegment(seq uint64) (*os.File, error) {
+	return os.OpenFile(s.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+func (s *FileStore) removeSegmentsBefore(seq uint64) error {
+	seqs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	for _, old := range seqs {
+		if old >= seq {
+			break
+		}
+		if err := os.Remove(s.segmentPath(old)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSnapshot atomically replaces the snapshot on disk.
+func (s *FileStore) writeSnapshot(snap snapshot) error {
+	tmp, err := ioutil.TempFile(s.dir, snapshotFile+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, snapshotFile))
+}
@@ -0,0 +1,174 @@
+package shortservice
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestFileStoreReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	open := func() *FileStore {
+		s, err := NewFileStore(dir, 0, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := open()
+	for _, kv := range [][2]string{{"a", "1"}, {"b", "2"}} {
+		if _, stored, err := s.PutIfAbsent(kv[0], Entry{V: kv[1]}); !stored || err != nil {
+			t.Fatalf("PutIfAbsent(%q): stored %v, err %v", kv[0], stored, err)
+		}
+	}
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	if _, stored, _ := s.PutIfAbsent("c", Entry{V: "3", Metadata: Metadata{Owner: "alice", CreatedAt: created}}); !stored {
+		t.Fatal("PutIfAbsent(c): not stored")
+	}
+	// Accesses are only persisted by compaction.
+	if _, err := s.Touch("c", created.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if _, stored, _ := s.PutIfAbsent("d", Entry{V: "4", ExpiresAt: time.Now()}); !stored {
+		t.Fatal("PutIfAbsent(d): not stored")
+	}
+	if n, err := s.Reap(time.Now()); n != 1 || err != nil {
+		t.Fatalf("Reap: want 1 removed, have %d (%v)", n, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = open()
+	defer s.Close()
+	for k, want := range map[string]string{"a": "1", "b": "2", "c": "3"} {
+		if have, err := s.Get(k); err != nil || have.V != want {
+			t.Errorf("Get(%q): want %q, have %q (%v)", k, want, have.V, err)
+		}
+	}
+	if _, err := s.Get("d"); err != ErrKeyNotFound {
+		t.Errorf("Get(d): want %v, have %v", ErrKeyNotFound, err)
+	}
+	want := Metadata{Owner: "alice", CreatedAt: created, AccessedAt: created.Add(time.Hour), Lookups: 1}
+	if have, _ := s.Get("c"); !have.CreatedAt.Equal(want.CreatedAt) || !have.AccessedAt.Equal(want.AccessedAt) ||
+		have.Owner != want.Owner || have.Lookups != want.Lookups {
+		t.Errorf("Get(c): want metadata %+v, have %+v", want, have.Metadata)
+	}
+	if old, stored, _ := s.PutIfAbsent("a", Entry{V: "x"}); stored || old.V != "1" {
+		t.Errorf("PutIfAbsent(a): want existing value 1, have %q (stored %v)", old.V, stored)
+	}
+	if items, err := s.Find("2"); err != nil || len(items) != 1 || items[0].K != "b" {
+		t.Errorf("Find(2): want b, have %v (%v)", items, err)
+	}
+	if items, err := s.Find("4"); err != nil || len(items) != 0 {
+		t.Errorf("Find(4): want nothing, have %v (%v)", items, err)
+	}
+}
+
+func TestFileStoreFailedAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewFileStore(dir, 0, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.PutIfAbsent("a", Entry{V: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A short write leaves a partial record, and the segment then fails
+	// every write.
+	f, err := os.OpenFile(s.wal.Name(), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"put","k":"b","v":`)
+	f.Close()
+	readOnly, err := os.Open(s.wal.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.wal.Close()
+	s.wal = readOnly
+
+	if _, _, err := s.PutIfAbsent("b", Entry{V: "2"}); err == nil {
+		t.Fatal("PutIfAbsent(b): want error from a failed write")
+	}
+	if _, stored, err := s.PutIfAbsent("c", Entry{V: "3"}); !stored || err != nil {
+		t.Fatalf("PutIfAbsent(c) after a failed write: stored %v, err %v", stored, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewFileStore(dir, 0, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("reopen after a failed write: %v", err)
+	}
+	defer s.Close()
+	for k, want := range map[string]string{"a": "1", "c": "3"} {
+		if have, err := s.Get(k); err != nil || have.V != want {
+			t.Errorf("Get(%q): want %q, have %q (%v)", k, want, have.V, err)
+		}
+	}
+	if _, err := s.Get("b"); err != ErrKeyNotFound {
+		t.Errorf("Get(b): want %v, have %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestFileStoreFailedCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewFileStore(dir, 0, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, _, err := s.PutIfAbsent("a", Entry{V: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A segment that fails to sync fails the compaction before the next
+	// segment is opened.
+	wal := s.wal
+	closed, err := os.Open(wal.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	s.wal = closed
+	if err := s.Compact(); err == nil {
+		t.Fatal("Compact: want error from a failed sync")
+	}
+	if _, err := os.Stat(s.segmentPath(s.seq + 1)); !os.IsNotExist(err) {
+		t.Errorf("want no segment opened by a failed compaction, have %v", err)
+	}
+
+	s.wal = wal
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact after a failed one: %v", err)
+	}
+}
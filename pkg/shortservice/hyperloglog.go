@@ -0,0 +1,55 @@
+package shortservice
+
+import (
+	"math"
+	"math/bits"
+)
+
+// hllPrecision is the number of hash bits that pick a register, for a
+// standard error of about 1.04/sqrt(2^hllPrecision), or 3%.
+const (
+	hllPrecision = 10
+	hllRegisters = 1 << hllPrecision
+)
+
+// hyperLogLog estimates the number of distinct hashes added to it in a fixed
+// amount of memory.
+type hyperLogLog [hllRegisters]uint8
+
+// add adds the 64 bit hash x.
+func (h *hyperLogLog) add(x uint64) {
+	i := x >> (64 - hllPrecision)
+	// The guard bit caps the rank when the remaining bits are all zero.
+	w := x<<hllPrecision | 1<<(hllPrecision-1)
+	if rank := uint8(bits.LeadingZeros64(w)) + 1; rank > h[i] {
+		h[i] = rank
+	}
+}
+
+// merge adds the hashes added to o.
+func (h *hyperLogLog) merge(o *hyperLogLog) {
+	for i, r := range o {
+		if r > h[i] {
+			h[i] = r
+		}
+	}
+}
+
+// estimate returns the estimated number of distinct hashes added.
+func (h *hyperLogLog) estimate() uint64 {
+	const m = float64(hllRegisters)
+	var sum float64
+	var zeros int
+	for _, r := range h {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// Small cardinalities are better estimated by counting empty registers.
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
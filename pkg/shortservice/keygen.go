@@ -0,0 +1,217 @@
+package shortservice
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"sync"
+	"sync/atomic"
+)
+
+// KeyGenerator proposes keys for new values.
+type KeyGenerator interface {
+	// Key returns the key to try for v on the given attempt, counting from
+	// zero. Create calls it with increasing attempts until it finds a free
+	// key, so each attempt should propose a different key.
+	Key(v string, attempt int) (string, error)
+	// Format describes the generated keys. The service only accepts keys
+	// in this format.
+	Format() KeyFormat
+}
+
+// errKeySpaceExhausted is returned when a generator has no more keys to
+// propose for a value.
+var errKeySpaceExhausted = errors.New("key space exhausted")
+
+// NewMD5Generator returns the default KeyGenerator. Keys are substrings of
+// the MD5 hash of the value encoded in the alphabet of f, so a value keeps
+// mapping to the same key as long as it is not taken by another value.
+func NewMD5Generator(f KeyFormat) KeyGenerator {
+	return hashGenerator{format: f, hash: md5.New}
+}
+
+// KeyHistory is implemented by generators whose keys for a value change
+// over time. Create reuses a live key that a previous generator issued for
+// the same value instead of creating a new one.
+type KeyHistory interface {
+	// Previous returns the generators used before, newest first.
+	Previous() []KeyGenerator
+}
+
+// HMACGenerator is a KeyGenerator like the MD5 one that uses an HMAC-SHA256
+// hash keyed with a secret instead, so keys can't be predicted without
+// knowing the secret. Secrets can be rotated at runtime. New keys are
+// derived from the current secret, and previous secrets are kept so values
+// shortened under them keep their keys.
+type HMACGenerator struct {
+	format   KeyFormat
+	mtx      sync.RWMutex
+	current  KeyGenerator
+	previous []KeyGenerator
+}
+
+// NewHMACGenerator returns an HMACGenerator keyed with secret. Previous
+// secrets are given newest first.
+func NewHMACGenerator(f KeyFormat, secret []byte, previous ...[]byte) *HMACGenerator {
+	g := &HMACGenerator{format: f}
+	g.SetSecrets(secret, previous...)
+	return g
+}
+
+// SetSecrets replaces the secrets of the generator, previous secrets newest
+// first. Rotating means passing a new secret and moving the current one to
+// the front of previous.
+func (g *HMACGenerator) SetSecrets(secret []byte, previous ...[]byte) {
+	current := hmacGenerator(g.format, secret)
+	prev := make([]KeyGenerator, len(previous))
+	for i, p := range previous {
+		prev[i] = hmacGenerator(g.format, p)
+	}
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.current, g.previous = current, prev
+}
+
+// Key implements KeyGenerator.
+func (g *HMACGenerator) Key(v string, attempt int) (string, error) {
+	g.mtx.RLock()
+	current := g.current
+	g.mtx.RUnlock()
+	return current.Key(v, attempt)
+}
+
+// Format implements KeyGenerator.
+func (g *HMACGenerator) Format() KeyFormat { return g.format }
+
+// Previous implements KeyHistory.
+func (g *HMACGenerator) Previous() []KeyGenerator {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	return g.previous
+}
+
+func hmacGenerator(f KeyFormat, secret []byte) KeyGenerator {
+	secret = append([]byte(nil), secret...)
+	return hashGenerator{format: f, hash: func() hash.Hash { return hmac.New(sha256.New, secret) }}
+}
+
+type hashGenerator struct {
+	format KeyFormat
+	hash   func() hash.Hash
+}
+
+// Format implements KeyGenerator.
+func (g hashGenerator) Format() KeyFormat { return g.format }
+
+// Key implements KeyGenerator. Attempts move a window along the encoded
+// hash. If every window of a size is taken the size increases and the scan
+// starts again, up to the full length of the hash.
+func (g hashGenerator) Key(v string, attempt int) (string, error) {
+	h := g.hash()
+	if _, err := h.Write([]byte(v)); err != nil {
+		return "", fmt.Errorf("failed to write hash: %v", err)
+	}
+	sum := g.format.Alphabet.encode(h.Sum(nil))
+
+	size := g.format.MinSize
+	if size > len(sum) {
+		return "", errKeySpaceExhausted
+	}
+	for windows := len(sum) - size + 1; attempt >= windows; windows = len(sum) - size + 1 {
+		attempt -= windows
+		size++
+		if size > len(sum) {
+			return "", errKeySpaceExhausted
+		}
+	}
+	return sum[attempt : attempt+size], nil
+}
+
+// NewCounterGenerator returns a KeyGenerator that encodes a monotonic
+// counter in the alphabet of f, zero padded to the minimum key size. Base62
+// is the usual choice. The counter starts after start and every call
+// advances it, so taken keys are skipped. CounterStart finds where a
+// restarted counter left off.
+func NewCounterGenerator(f KeyFormat, start uint64) KeyGenerator {
+	return &counterGenerator{format: f, n: start}
+}
+
+type counterGenerator struct {
+	format KeyFormat
+	n      uint64
+}
+
+// Key implements KeyGenerator.
+func (g *counterGenerator) Key(string, int) (string, error) {
+	n := atomic.AddUint64(&g.n, 1)
+	return g.format.Alphabet.format(new(big.Int).SetUint64(n), g.format.MinSize), nil
+}
+
+// Format implements KeyGenerator.
+func (g *counterGenerator) Format() KeyFormat { return g.format }
+
+// CounterStart returns the largest counter among the keys in store that a
+// counter generator with format f could have issued, so one restarted with
+// it doesn't walk through the keys it issued before. Custom keys in that
+// format count too.
+func CounterStart(store Store, f KeyFormat) (uint64, error) {
+	var start uint64
+	err := store.Range("", func(k string, _ Entry) bool {
+		// Keys longer than the minimum size never start with a zero.
+		if len(k) < f.MinSize || (len(k) > f.MinSize && k[0] == f.Alphabet.chars[0]) {
+			return true
+		}
+		n, ok := f.Alphabet.parse(k)
+		if ok && n.IsUint64() && n.Uint64() > start {
+			start = n.Uint64()
+		}
+		return true
+	})
+	return start, err
+}
+
+// randomGrowEvery is how many collisions a random key of one size may have
+// before the next attempts use longer keys.
+const randomGrowEvery = 8
+
+// NewRandomGenerator returns a KeyGenerator that draws keys uniformly from
+// the alphabet of f using crypto/rand.
+func NewRandomGenerator(f KeyFormat) KeyGenerator {
+	return randomGenerator{format: f}
+}
+
+type randomGenerator struct {
+	format KeyFormat
+}
+
+// Key implements KeyGenerator.
+func (g randomGenerator) Key(_ string, attempt int) (string, error) {
+	var (
+		chars = g.format.Alphabet.chars
+		// Bytes from limit up are rejected, so every character is
+		// equally likely.
+		limit = 256 - 256%len(chars)
+		key   = make([]byte, 0, g.format.MinSize+attempt/randomGrowEvery)
+		buf   = make([]byte, cap(key))
+	)
+	for len(key) < cap(key) {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to read random key: %v", err)
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(key) < cap(key) {
+				key = append(key, chars[int(b)%len(chars)])
+			}
+		}
+	}
+	return string(key), nil
+}
+
+// Format implements KeyGenerator.
+func (g randomGenerator) Format() KeyFormat { return g.format }
@@ -0,0 +1,136 @@
+package shortservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+func TestHashGeneratorWindows(t *testing.T) {
+	g := NewMD5Generator(DefaultKeyFormat)
+	// The base64 MD5 hash of 12345 is gnzLDuqKcGxMNKFokfhOew.
+	for _, testcase := range []struct {
+		attempt int
+		want    string
+	}{
+		{0, "gnzLDu"},
+		{1, "nzLDuq"},
+		{16, "kfhOew"}, // last window of 6
+		{17, "gnzLDuq"},
+	} {
+		if have, err := g.Key("12345", testcase.attempt); have != testcase.want || err != nil {
+			t.Errorf("Key(12345, %d): want %q, have %q (%v)", testcase.attempt, testcase.want, have, err)
+		}
+	}
+	if _, err := g.Key("12345", 1000); err != errKeySpaceExhausted {
+		t.Errorf("Key(12345, 1000): want %v, have %v", errKeySpaceExhausted, err)
+	}
+}
+
+func TestCounterStart(t *testing.T) {
+	f := KeyFormat{Alphabet: Base62, MinSize: 4}
+	g := NewCounterGenerator(f, 0)
+	store := NewInMemStore()
+	for i := 0; i < 5; i++ {
+		k, _ := g.Key("", 0)
+		store.PutIfAbsent(k, Entry{V: k})
+	}
+	// Deletes leave fewer keys than were issued, and keys the counter
+	// couldn't have issued are left out.
+	store.Delete("0001")
+	store.Delete("0002")
+	for _, k := range []string{"zz", "0zzzz", "spring-sale"} {
+		store.PutIfAbsent(k, Entry{V: k})
+	}
+
+	start, err := CounterStart(store, f)
+	if start != 5 || err != nil {
+		t.Fatalf("CounterStart: want 5, have %d (%v)", start, err)
+	}
+	if k, _ := NewCounterGenerator(f, start).Key("", 0); k != "0006" {
+		t.Errorf("Key after restart: want 0006, have %q", k)
+	}
+}
+
+func TestKeyGenerators(t *testing.T) {
+	for name, g := range map[string]KeyGenerator{
+		"md5":     NewMD5Generator(DefaultKeyFormat),
+		"hmac":    NewHMACGenerator(DefaultKeyFormat, []byte("secret")),
+		"counter": NewCounterGenerator(DefaultKeyFormat, 0),
+		"random":  NewRandomGenerator(DefaultKeyFormat),
+	} {
+		seen := map[string]bool{}
+		for attempt := 0; attempt < 10; attempt++ {
+			k, err := g.Key("http://a.com", attempt)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if len(k) < DefaultKeyFormat.MinSize || !DefaultKeyFormat.Alphabet.Valid(k) || seen[k] {
+				t.Errorf("%s: attempt %d: bad or repeated key %q", name, attempt, k)
+			}
+			seen[k] = true
+		}
+	}
+}
+
+func TestHMACRotation(t *testing.T) {
+	g := NewHMACGenerator(DefaultKeyFormat, []byte("old"))
+	svc := NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(), WithKeyGenerator(g))
+	ctx := context.Background()
+
+	k1, err := svc.Create(ctx, "http://a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetSecrets([]byte("new"), []byte("old"))
+
+	if k, err := svc.Create(ctx, "http://a.com"); k != k1 || err != nil {
+		t.Errorf("Create after rotation: want existing key %q, have %q (%v)", k1, k, err)
+	}
+	want, _ := NewHMACGenerator(DefaultKeyFormat, []byte("new")).Key("http://b.com", 0)
+	if k, err := svc.Create(ctx, "http://b.com"); k != want || err != nil {
+		t.Errorf("Create after rotation: want key %q from the new secret, have %q (%v)", want, k, err)
+	}
+}
+
+func TestKeyAlphabets(t *testing.T) {
+	ctx := context.Background()
+	for _, a := range Alphabets {
+		f := KeyFormat{Alphabet: a, MinSize: 4}
+		svc := NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(), WithKeyGenerator(NewMD5Generator(f)))
+
+		k, err := svc.Create(ctx, "http://a.com")
+		if err != nil {
+			t.Fatalf("%s: %v", a, err)
+		}
+		if len(k) != f.MinSize || !a.Valid(k) {
+			t.Errorf("%s: bad key %q", a, k)
+		}
+		if v, err := svc.Lookup(ctx, k); v != "http://a.com" || err != nil {
+			t.Errorf("%s: Lookup(%q): want http://a.com, have %q (%v)", a, k, v, err)
+		}
+		if _, err := svc.Lookup(ctx, k[:3]+"!"); err != ErrInvalidKey {
+			t.Errorf("%s: Lookup with invalid character: want %v, have %v", a, ErrInvalidKey, err)
+		}
+	}
+}
+
+func TestCrockfordKeys(t *testing.T) {
+	svc := NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(),
+		WithKeyGenerator(NewMD5Generator(KeyFormat{Alphabet: Crockford32, MinSize: 6})))
+	ctx := context.Background()
+
+	if k, err := svc.Create(ctx, "http://a.com", WithKey("H0ME")); k != "H0ME" || err != nil {
+		t.Fatalf("Create: want H0ME, have %q (%v)", k, err)
+	}
+	for _, k := range []string{"H0ME", "h0me", "hOme", "home"} {
+		if v, err := svc.Lookup(ctx, k); v != "http://a.com" || err != nil {
+			t.Errorf("Lookup(%q): want http://a.com, have %q (%v)", k, v, err)
+		}
+	}
+	if _, err := svc.Lookup(ctx, "HUME"); err != ErrInvalidKey {
+		t.Errorf("Lookup(HUME): want %v, have %v", ErrInvalidKey, err)
+	}
+}
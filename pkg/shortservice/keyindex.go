@@ -0,0 +1,89 @@
+package shortservice
+
+import "sort"
+
+// keyBlockSize is the most keys a block of a keyIndex holds before it is
+// split in two.
+const keyBlockSize = 512
+
+// keyIndex keeps keys in order, so ranges over them don't sort the whole
+// store. Keys are held in sorted blocks of at most keyBlockSize, which keeps
+// inserts and removals cheap without the bookkeeping of a tree.
+type keyIndex struct {
+	blocks [][]string // non empty, each sorted and before the next
+}
+
+// block returns the index of the first block whose last key is at least k,
+// or len(x.blocks) if there is none.
+func (x *keyIndex) block(k string) int {
+	return sort.Search(len(x.blocks), func(i int) bool {
+		b := x.blocks[i]
+		return b[len(b)-1] >= k
+	})
+}
+
+// insert adds k to the index, unless it is already there.
+func (x *keyIndex) insert(k string) {
+	if len(x.blocks) == 0 {
+		x.blocks = [][]string{{k}}
+		return
+	}
+	n := x.block(k)
+	if n == len(x.blocks) {
+		n--
+	}
+	b := x.blocks[n]
+	i := sort.SearchStrings(b, k)
+	if i < len(b) && b[i] == k {
+		return
+	}
+	b = append(b, "")
+	copy(b[i+1:], b[i:])
+	b[i] = k
+	if len(b) <= keyBlockSize {
+		x.blocks[n] = b
+		return
+	}
+
+	half := len(b) / 2
+	right := append([]string(nil), b[half:]...)
+	x.blocks = append(x.blocks, nil)
+	copy(x.blocks[n+2:], x.blocks[n+1:])
+	x.blocks[n], x.blocks[n+1] = b[:half:half], right
+}
+
+// remove removes k from the index, if it is there.
+func (x *keyIndex) remove(k string) {
+	n := x.block(k)
+	if n == len(x.blocks) {
+		return
+	}
+	b := x.blocks[n]
+	i := sort.SearchStrings(b, k)
+	if i == len(b) || b[i] != k {
+		return
+	}
+	b = append(b[:i], b[i+1:]...)
+	if len(b) == 0 {
+		x.blocks = append(x.blocks[:n], x.blocks[n+1:]...)
+		return
+	}
+	x.blocks[n] = b
+}
+
+// ascend calls fn for the keys greater than after, in order, until fn
+// returns false.
+func (x *keyIndex) ascend(after string, fn func(k string) bool) {
+	n := sort.Search(len(x.blocks), func(i int) bool {
+		b := x.blocks[i]
+		return b[len(b)-1] > after
+	})
+	for ; n < len(x.blocks); n++ {
+		b := x.blocks[n]
+		for i := sort.Search(len(b), func(i int) bool { return b[i] > after }); i < len(b); i++ {
+			if !fn(b[i]) {
+				return
+			}
+		}
+	}
+}
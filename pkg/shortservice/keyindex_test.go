@@ -0,0 +1,58 @@
+package shortservice
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestKeyIndex(t *testing.T) {
+	var x keyIndex
+	want := map[string]bool{}
+	r := rand.New(rand.NewSource(1))
+	// Enough keys to split blocks, and enough removals to empty some.
+	for i := 0; i < 20000; i++ {
+		k := fmt.Sprintf("%04d", r.Intn(5000))
+		if r.Intn(3) == 0 {
+			x.remove(k)
+			delete(want, k)
+		} else {
+			x.insert(k)
+			want[k] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(want))
+	for k := range want {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, b := range x.blocks {
+		if len(b) == 0 || len(b) > keyBlockSize {
+			t.Fatalf("block of %d keys", len(b))
+		}
+	}
+
+	for _, after := range []string{"", "0000", "1234", "2500x", sorted[len(sorted)-1], "9999"} {
+		var have []string
+		x.ascend(after, func(k string) bool {
+			have = append(have, k)
+			return true
+		})
+		i := sort.Search(len(sorted), func(i int) bool { return sorted[i] > after })
+		if wantAfter := sorted[i:]; len(wantAfter) != len(have) || (len(have) > 0 && !reflect.DeepEqual(wantAfter, have)) {
+			t.Errorf("ascend(%q): want %d keys in order, have %d", after, len(wantAfter), len(have))
+		}
+	}
+
+	var n int
+	x.ascend("", func(string) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Errorf("ascend stopped after %d keys, want 10", n)
+	}
+}
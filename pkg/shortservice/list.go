@@ -0,0 +1,116 @@
+package shortservice
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCursor protects the List method from cursors it didn't
+	// return.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidLimit protects the List method from negative limits and
+	// limits above MaxListLimit.
+	ErrInvalidLimit = errors.New("invalid limit")
+)
+
+const (
+	// DefaultListLimit is how many entries List returns when no limit is
+	// given.
+	DefaultListLimit = 100
+	// MaxListLimit is the most entries List returns at once.
+	MaxListLimit = 1000
+)
+
+// Filter selects the entries returned by List. Zero fields select every
+// entry.
+type Filter struct {
+	// Owner selects the entries created by an owner.
+	Owner string `json:"owner,omitempty"`
+	// Prefix selects the entries whose value starts with it.
+	Prefix string `json:"prefix,omitempty"`
+	// From and To select the entries created within [From, To).
+	From time.Time `json:"from,omitempty"`
+	To   time.Time `json:"to,omitempty"`
+}
+
+// match reports whether e is selected by f.
+func (f Filter) match(e Entry) bool {
+	return (f.Owner == "" || e.Owner == f.Owner) &&
+		strings.HasPrefix(e.V, f.Prefix) &&
+		(f.From.IsZero() || !e.CreatedAt.Before(f.From)) &&
+		(f.To.IsZero() || e.CreatedAt.Before(f.To))
+}
+
+// Item is an entry returned by List, along with its key.
+type Item struct {
+	K string `json:"k"`
+	Entry
+}
+
+// Page is a page of entries returned by List.
+type Page struct {
+	Items []Item `json:"items"`
+	// Cursor resumes the listing after the last item, and is empty once
+	// there are no more.
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// encodeCursor returns the cursor resuming a listing after key k. Cursors
+// are opaque to callers so the order they encode may change.
+func encodeCursor(k string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(k))
+}
+
+// decodeCursor returns the key a cursor resumes after.
+func decodeCursor(cursor string) (string, error) {
+	k, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || (cursor != "" && len(k) == 0) {
+		return "", ErrInvalidCursor
+	}
+	return string(k), nil
+}
+
+// List implements Service. Entries are listed in key order, so entries
+// created while paging show up in later pages if their key sorts after the
+// cursor. Expired entries are listed until they are reaped.
+func (s *service) List(_ context.Context, cursor string, limit int, filter Filter) (Page, error) {
+	if limit < 0 || limit > MaxListLimit {
+		return Page{}, ErrInvalidLimit
+	}
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return Page{}, ErrInvalidRange
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+
+	// One more item than asked for tells whether there are more pages.
+	page := Page{Items: []Item{}}
+	var more bool
+	err = s.store.Range(after, func(k string, e Entry) bool {
+		if !filter.match(e) {
+			return true
+		}
+		if len(page.Items) == limit {
+			more = true
+			return false
+		}
+		page.Items = append(page.Items, Item{K: k, Entry: e})
+		return true
+	})
+	if err != nil {
+		return Page{}, err
+	}
+	if more {
+		page.Cursor = encodeCursor(page.Items[len(page.Items)-1].K)
+	}
+	return page, nil
+}
@@ -3,8 +3,11 @@ package shortservice
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/go-kit/kit/metrics"
 )
 
@@ -12,63 +15,273 @@ import (
 type Middleware func(Service) Service
 
 // LoggingMiddleware takes a logger as a dependency
-// and returns a ServiceMiddleware.
-func LoggingMiddleware(logger log.Logger) Middleware {
+// and returns a ServiceMiddleware. Values are redacted and lookups sampled
+// as set by opts. Internal errors are logged at error level and everything
+// else at info level.
+func LoggingMiddleware(logger log.Logger, opts ...LoggingOption) Middleware {
+	o := NewLoggingOptions(opts...)
 	return func(next Service) Service {
-		return loggingMiddleware{logger, next}
+		return loggingMiddleware{logger, o.Redaction, NewSampler(o.LookupSampling), next}
 	}
 }
 
 type loggingMiddleware struct {
-	logger log.Logger
-	next   Service
+	logger  log.Logger
+	redact  Redaction
+	lookups *Sampler
+	next    Service
+}
+
+// log logs keyvals at the level for err.
+func (mw loggingMiddleware) log(err error, keyvals ...interface{}) {
+	if errorKind(err) == errorKindInternal {
+		level.Error(mw.logger).Log(keyvals...)
+		return
+	}
+	level.Info(mw.logger).Log(keyvals...)
+}
+
+// sampled reports whether a lookup that returned err is logged.
+func (mw loggingMiddleware) sampled(err error) bool {
+	return errorKind(err) == errorKindInternal || mw.lookups.Sample()
 }
 
-func (mw loggingMiddleware) Create(ctx context.Context, v string) (k string, err error) {
+func (mw loggingMiddleware) Create(ctx context.Context, v string, opts ...CreateOption) (k string, err error) {
 	defer func() {
-		mw.logger.Log("method", "Create", "v", v, "k", k, "err", err)
+		o := NewCreateOptions(opts...)
+		mw.log(err, "method", "Create", "v", mw.redact.Redact(v), "ttl", o.TTL, "owner", o.Owner, "key", o.Key, "k", k, "err", err)
 	}()
-	return mw.next.Create(ctx, v)
+	return mw.next.Create(ctx, v, opts...)
 }
 
 func (mw loggingMiddleware) Lookup(ctx context.Context, k string) (v string, err error) {
 	defer func() {
-		mw.logger.Log("method", "Lookup", "k", k, "v", v, "err", err)
+		if mw.sampled(err) {
+			mw.log(err, "method", "Lookup", "k", k, "v", mw.redact.Redact(v), "err", err)
+		}
 	}()
 	return mw.next.Lookup(ctx, k)
 }
 
+func (mw loggingMiddleware) Stat(ctx context.Context, k string) (m Metadata, err error) {
+	defer func() {
+		mw.log(err, "method", "Stat", "k", k, "err", err)
+	}()
+	return mw.next.Stat(ctx, k)
+}
+
+func (mw loggingMiddleware) Update(ctx context.Context, k, v string) (err error) {
+	defer func() {
+		mw.log(err, "method", "Update", "k", k, "v", mw.redact.Redact(v), "err", err)
+	}()
+	return mw.next.Update(ctx, k, v)
+}
+
+func (mw loggingMiddleware) Delete(ctx context.Context, k string) (err error) {
+	defer func() {
+		mw.log(err, "method", "Delete", "k", k, "err", err)
+	}()
+	return mw.next.Delete(ctx, k)
+}
+
+func (mw loggingMiddleware) CreateBatch(ctx context.Context, vs []string, opts ...CreateOption) (results []Result, err error) {
+	defer func() {
+		o := NewCreateOptions(opts...)
+		mw.log(err, "method", "CreateBatch", "n", len(vs), "ttl", o.TTL, "owner", o.Owner, "failed", failed(results), "err", err)
+	}()
+	return mw.next.CreateBatch(ctx, vs, opts...)
+}
+
+func (mw loggingMiddleware) LookupBatch(ctx context.Context, ks []string) (results []Result, err error) {
+	defer func() {
+		if mw.sampled(err) {
+			mw.log(err, "method", "LookupBatch", "n", len(ks), "failed", failed(results), "err", err)
+		}
+	}()
+	return mw.next.LookupBatch(ctx, ks)
+}
+
+func (mw loggingMiddleware) Analytics(ctx context.Context, k string, from, to time.Time) (a Analytics, err error) {
+	defer func() {
+		mw.log(err, "method", "Analytics", "k", k, "from", from, "to", to, "err", err)
+	}()
+	return mw.next.Analytics(ctx, k, from, to)
+}
+
+func (mw loggingMiddleware) List(ctx context.Context, cursor string, limit int, filter Filter) (page Page, err error) {
+	defer func() {
+		mw.log(err, "method", "List", "cursor", cursor, "limit", limit, "owner", filter.Owner, "prefix", mw.redact.Redact(filter.Prefix), "from", filter.From, "to", filter.To, "n", len(page.Items), "err", err)
+	}()
+	return mw.next.List(ctx, cursor, limit, filter)
+}
+
+func (mw loggingMiddleware) Find(ctx context.Context, v string) (k string, err error) {
+	defer func() {
+		mw.log(err, "method", "Find", "v", mw.redact.Redact(v), "k", k, "err", err)
+	}()
+	return mw.next.Find(ctx, v)
+}
+
+// failed counts the results of a batch that carry an error.
+func failed(results []Result) int {
+	var n int
+	for _, r := range results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
 // InstrumentingMiddleware returns a service middleware that instruments
-// the number of creations and lookups over the lifetime of
-// the service.
-func InstrumentingMiddleware(inserts, lookups metrics.Counter) Middleware {
+// the number of creations, lookups, updates and deletions over the
+// lifetime of the service. Every call is counted with its method, whether
+// it succeeded and the kind of error it failed with. The share of looked up
+// keys that were found is kept in hitRatio.
+func InstrumentingMiddleware(inserts, lookups, updates, deletes metrics.Counter, hitRatio metrics.Gauge) Middleware {
 	return func(next Service) Service {
 		return instrumentingMiddleware{
-			inserts: inserts,
-			lookups: lookups,
-			next:    next,
+			inserts:  inserts,
+			lookups:  lookups,
+			updates:  updates,
+			deletes:  deletes,
+			hitRatio: hitRatio,
+			hits:     &hits{},
+			next:     next,
 		}
 	}
 }
 
 type instrumentingMiddleware struct {
-	inserts metrics.Counter
-	lookups metrics.Counter
-	next    Service
+	inserts  metrics.Counter
+	lookups  metrics.Counter
+	updates  metrics.Counter
+	deletes  metrics.Counter
+	hitRatio metrics.Gauge
+	hits     *hits
+	next     Service
+}
+
+// hits counts lookups that found their key and lookups that missed.
+type hits struct {
+	mtx          sync.Mutex
+	hits, misses float64
 }
 
-func (mw instrumentingMiddleware) Create(ctx context.Context, v string) (string, error) {
-	v, err := mw.next.Create(ctx, v)
-	if err != nil {
-		mw.inserts.With("method", "Create", "success", fmt.Sprint(err == nil)).Add(1)
+// Error kinds of the error label.
+const (
+	errorKindNone     = "none"
+	errorKindNotFound = "not_found"
+	errorKindTooLarge = "too_large"
+	errorKindInvalid  = "invalid"
+	errorKindTaken    = "taken"
+	errorKindDisabled = "disabled"
+	errorKindInternal = "internal"
+)
+
+// errorKind classifies err for the error label.
+func errorKind(err error) string {
+	switch err {
+	case nil:
+		return errorKindNone
+	case ErrKeyNotFound, ErrKeyExpired:
+		return errorKindNotFound
+	case ErrMaxSizeExceeded:
+		return errorKindTooLarge
+	case ErrInvalidKey, ErrInvalidTTL, ErrInvalidRange, ErrInvalidCursor, ErrInvalidLimit:
+		return errorKindInvalid
+	case ErrKeyTaken:
+		return errorKindTaken
+	case ErrAnalyticsDisabled:
+		return errorKindDisabled
+	default:
+		return errorKindInternal
 	}
-	return v, err
+}
+
+// count adds a call of method that returned err to c.
+func count(c metrics.Counter, method string, err error) {
+	c.With("method", method, "success", fmt.Sprint(err == nil), "error", errorKind(err)).Add(1)
+}
+
+// observe records the outcome of looking up a key. Errors other than a
+// missing key are neither hits nor misses.
+func (mw instrumentingMiddleware) observe(err error) {
+	kind := errorKind(err)
+	if kind != errorKindNone && kind != errorKindNotFound {
+		return
+	}
+
+	mw.hits.mtx.Lock()
+	defer mw.hits.mtx.Unlock()
+	if kind == errorKindNone {
+		mw.hits.hits++
+	} else {
+		mw.hits.misses++
+	}
+	mw.hitRatio.Set(mw.hits.hits / (mw.hits.hits + mw.hits.misses))
+}
+
+func (mw instrumentingMiddleware) Create(ctx context.Context, v string, opts ...CreateOption) (string, error) {
+	k, err := mw.next.Create(ctx, v, opts...)
+	count(mw.inserts, "Create", err)
+	return k, err
 }
 
 func (mw instrumentingMiddleware) Lookup(ctx context.Context, k string) (string, error) {
 	v, err := mw.next.Lookup(ctx, k)
-	if err != nil {
-		mw.lookups.With("method", "Lookup", "success", fmt.Sprint(err == nil)).Add(1)
-	}
+	count(mw.lookups, "Lookup", err)
+	mw.observe(err)
 	return v, err
 }
+
+func (mw instrumentingMiddleware) Stat(ctx context.Context, k string) (Metadata, error) {
+	m, err := mw.next.Stat(ctx, k)
+	count(mw.lookups, "Stat", err)
+	return m, err
+}
+
+func (mw instrumentingMiddleware) Update(ctx context.Context, k, v string) error {
+	err := mw.next.Update(ctx, k, v)
+	count(mw.updates, "Update", err)
+	return err
+}
+
+func (mw instrumentingMiddleware) Delete(ctx context.Context, k string) error {
+	err := mw.next.Delete(ctx, k)
+	count(mw.deletes, "Delete", err)
+	return err
+}
+
+func (mw instrumentingMiddleware) CreateBatch(ctx context.Context, vs []string, opts ...CreateOption) ([]Result, error) {
+	results, err := mw.next.CreateBatch(ctx, vs, opts...)
+	count(mw.inserts, "CreateBatch", err)
+	return results, err
+}
+
+func (mw instrumentingMiddleware) LookupBatch(ctx context.Context, ks []string) ([]Result, error) {
+	results, err := mw.next.LookupBatch(ctx, ks)
+	count(mw.lookups, "LookupBatch", err)
+	for _, r := range results {
+		mw.observe(r.Err)
+	}
+	return results, err
+}
+
+func (mw instrumentingMiddleware) Analytics(ctx context.Context, k string, from, to time.Time) (Analytics, error) {
+	a, err := mw.next.Analytics(ctx, k, from, to)
+	count(mw.lookups, "Analytics", err)
+	return a, err
+}
+
+func (mw instrumentingMiddleware) List(ctx context.Context, cursor string, limit int, filter Filter) (Page, error) {
+	page, err := mw.next.List(ctx, cursor, limit, filter)
+	count(mw.lookups, "List", err)
+	return page, err
+}
+
+func (mw instrumentingMiddleware) Find(ctx context.Context, v string) (string, error) {
+	k, err := mw.next.Find(ctx, v)
+	count(mw.lookups, "Find", err)
+	return k, err
+}
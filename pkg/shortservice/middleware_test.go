@@ -0,0 +1,111 @@
+package shortservice
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestInstrumentingMiddleware(t *testing.T) {
+	var (
+		inserts  = newLabeledCounter()
+		lookups  = newLabeledCounter()
+		updates  = newLabeledCounter()
+		deletes  = newLabeledCounter()
+		hitRatio = generic.NewGauge("hit_ratio")
+	)
+	svc := NewInMemService(log.NewNopLogger(), inserts, lookups, WithChangeCounters(updates, deletes), WithHitRatio(hitRatio))
+	ctx := context.Background()
+
+	k, _ := svc.Create(ctx, "http://a.com")
+	svc.Create(ctx, strings.Repeat("a", maxLen+1))
+	svc.Lookup(ctx, k)
+	svc.Lookup(ctx, k)
+	svc.Lookup(ctx, "nope")
+	svc.LookupBatch(ctx, []string{k, "nope"})
+	svc.Update(ctx, k, "http://b.com")
+	svc.Delete(ctx, k)
+	svc.Delete(ctx, k)
+
+	for _, testcase := range []struct {
+		c      *labeledCounter
+		labels string
+		want   float64
+	}{
+		{inserts, "method=Create,success=true,error=none", 1},
+		{inserts, "method=Create,success=false,error=too_large", 1},
+		{lookups, "method=Lookup,success=true,error=none", 2},
+		{lookups, "method=Lookup,success=false,error=not_found", 1},
+		{lookups, "method=LookupBatch,success=true,error=none", 1},
+		{updates, "method=Update,success=true,error=none", 1},
+		{deletes, "method=Delete,success=true,error=none", 1},
+		{deletes, "method=Delete,success=false,error=not_found", 1},
+	} {
+		if have := testcase.c.value(testcase.labels); have != testcase.want {
+			t.Errorf("%s: want %v, have %v", testcase.labels, testcase.want, have)
+		}
+	}
+	// Three hits and two misses, including the batch.
+	if want, have := 0.6, hitRatio.Value(); want != have {
+		t.Errorf("hit ratio: want %v, have %v", want, have)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	for err, want := range map[error]string{
+		nil:                  "none",
+		ErrKeyNotFound:       "not_found",
+		ErrKeyExpired:        "not_found",
+		ErrMaxSizeExceeded:   "too_large",
+		ErrInvalidKey:        "invalid",
+		ErrInvalidRange:      "invalid",
+		ErrInvalidCursor:     "invalid",
+		ErrInvalidLimit:      "invalid",
+		ErrKeyTaken:          "taken",
+		ErrAnalyticsDisabled: "disabled",
+		errKeySpaceExhausted: "internal",
+	} {
+		if have := errorKind(err); want != have {
+			t.Errorf("errorKind(%v): want %q, have %q", err, want, have)
+		}
+	}
+}
+
+// labeledCounter is a metrics.Counter that keeps a generic.Counter for every
+// set of label values, since generic counters don't share values across
+// With.
+type labeledCounter struct {
+	lvs      []string
+	counters map[string]*generic.Counter
+}
+
+func newLabeledCounter() *labeledCounter {
+	return &labeledCounter{counters: map[string]*generic.Counter{}}
+}
+
+func (c *labeledCounter) With(labelValues ...string) metrics.Counter {
+	return &labeledCounter{lvs: append(append([]string(nil), c.lvs...), labelValues...), counters: c.counters}
+}
+
+func (c *labeledCounter) Add(delta float64) {
+	var pairs []string
+	for i := 0; i+1 < len(c.lvs); i += 2 {
+		pairs = append(pairs, c.lvs[i]+"="+c.lvs[i+1])
+	}
+	key := strings.Join(pairs, ",")
+	if c.counters[key] == nil {
+		c.counters[key] = generic.NewCounter(key)
+	}
+	c.counters[key].Add(delta)
+}
+
+func (c *labeledCounter) value(labels string) float64 {
+	if c.counters[labels] == nil {
+		return 0
+	}
+	return c.counters[labels].Value()
+}
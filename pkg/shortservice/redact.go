@@ -0,0 +1,121 @@
+package shortservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+)
+
+// Redaction decides how much of a value is logged. Values often carry
+// tokens in their query strings, so they shouldn't be logged verbatim.
+type Redaction int
+
+const (
+	// RedactNone logs values verbatim.
+	RedactNone Redaction = iota
+	// RedactHost logs the scheme and host of URL values, and nothing of
+	// other values.
+	RedactHost
+	// RedactHash logs a hash of values, so lines about the same value can
+	// be correlated without revealing it.
+	RedactHash
+	// RedactFull logs nothing of values.
+	RedactFull
+)
+
+// redacted replaces the parts of values that aren't logged.
+const redacted = "[redacted]"
+
+var redactionNames = map[Redaction]string{
+	RedactNone: "none",
+	RedactHost: "host",
+	RedactHash: "hash",
+	RedactFull: "full",
+}
+
+// ParseRedaction returns the Redaction with the given name: none, host,
+// hash or full.
+func ParseRedaction(name string) (Redaction, error) {
+	for r, n := range redactionNames {
+		if n == name {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown redaction %q", name)
+}
+
+// String returns the name of the redaction.
+func (r Redaction) String() string { return redactionNames[r] }
+
+// Redact returns what is logged of v.
+func (r Redaction) Redact(v string) string {
+	switch r {
+	case RedactNone:
+		return v
+	case RedactHost:
+		u, err := url.Parse(v)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return redacted
+		}
+		return u.Scheme + "://" + u.Host + "/" + redacted
+	case RedactHash:
+		sum := sha256.Sum256([]byte(v))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	default:
+		return redacted
+	}
+}
+
+// LoggingOptions configures the logging middlewares.
+type LoggingOptions struct {
+	// Redaction decides how much of values is logged.
+	Redaction Redaction
+	// LookupSampling logs one in every LookupSampling lookups. Internal
+	// errors are always logged. Zero or one logs every lookup.
+	LookupSampling int
+}
+
+// LoggingOption sets a parameter of the logging middlewares.
+type LoggingOption func(*LoggingOptions)
+
+// WithRedaction makes the logging middlewares redact values with r.
+func WithRedaction(r Redaction) LoggingOption {
+	return func(o *LoggingOptions) { o.Redaction = r }
+}
+
+// WithLookupSampling makes the logging middlewares log one in every n
+// lookups.
+func WithLookupSampling(n int) LoggingOption {
+	return func(o *LoggingOptions) { o.LookupSampling = n }
+}
+
+// NewLoggingOptions applies opts to a zero LoggingOptions.
+func NewLoggingOptions(opts ...LoggingOption) LoggingOptions {
+	var o LoggingOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Sampler picks one in every n events.
+type Sampler struct {
+	n     uint64
+	count uint64
+}
+
+// NewSampler returns a Sampler that picks one in every n events, starting
+// with the first. Zero or one picks every event.
+func NewSampler(n int) *Sampler {
+	if n < 1 {
+		n = 1
+	}
+	return &Sampler{n: uint64(n)}
+}
+
+// Sample reports whether the next event is picked.
+func (s *Sampler) Sample() bool {
+	return (atomic.AddUint64(&s.count, 1)-1)%s.n == 0
+}
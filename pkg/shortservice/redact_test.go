@@ -0,0 +1,61 @@
+package shortservice
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+func TestRedaction(t *testing.T) {
+	const v = "https://a.com/reset?token=secret"
+	for _, testcase := range []struct {
+		r    Redaction
+		v    string
+		want string
+	}{
+		{RedactNone, v, v},
+		{RedactHost, v, "https://a.com/[redacted]"},
+		{RedactHost, "token=secret", "[redacted]"},
+		{RedactHash, v, "sha256:2793429b2277692e"},
+		{RedactFull, v, "[redacted]"},
+	} {
+		if have := testcase.r.Redact(testcase.v); have != testcase.want {
+			t.Errorf("%s: Redact(%q): want %q, have %q", testcase.r, testcase.v, testcase.want, have)
+		}
+	}
+
+	for _, r := range []Redaction{RedactNone, RedactHost, RedactHash, RedactFull} {
+		if have, err := ParseRedaction(r.String()); have != r || err != nil {
+			t.Errorf("ParseRedaction(%q): want %v, have %v (%v)", r, r, have, err)
+		}
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	svc := NewInMemService(log.NewLogfmtLogger(&buf), discard.NewCounter(), discard.NewCounter(),
+		WithLogging(WithRedaction(RedactHost), WithLookupSampling(3)))
+	ctx := context.Background()
+
+	k, err := svc.Create(ctx, "https://a.com/reset?token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		svc.Lookup(ctx, k)
+	}
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("value logged unredacted:\n%s", buf.String())
+	}
+	if want, have := 2, strings.Count(buf.String(), "method=Lookup"); want != have {
+		t.Errorf("want %d sampled lookups logged, have %d:\n%s", want, have, buf.String())
+	}
+	if want, have := 3, strings.Count(buf.String(), "level=info"); want != have {
+		t.Errorf("want %d lines at level info, have %d:\n%s", want, have, buf.String())
+	}
+}
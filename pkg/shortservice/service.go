@@ -2,30 +2,170 @@ package shortservice
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/base64"
 	"errors"
-	"fmt"
-	"hash"
-	"sync"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
 )
 
 // Service describes a service that generates and stores URL safe short keys for strings.
 type Service interface {
-	Create(ctx context.Context, v string) (string, error)
+	Create(ctx context.Context, v string, opts ...CreateOption) (string, error)
 	Lookup(ctx context.Context, k string) (string, error)
+	Stat(ctx context.Context, k string) (Metadata, error)
+	Update(ctx context.Context, k, v string) error
+	Delete(ctx context.Context, k string) error
+	CreateBatch(ctx context.Context, vs []string, opts ...CreateOption) ([]Result, error)
+	LookupBatch(ctx context.Context, ks []string) ([]Result, error)
+	Analytics(ctx context.Context, k string, from, to time.Time) (Analytics, error)
+	List(ctx context.Context, cursor string, limit int, filter Filter) (Page, error)
+	Find(ctx context.Context, v string) (string, error)
+}
+
+// Result is the outcome of a single item of a batch operation.
+type Result struct {
+	K   string `json:"k,omitempty"`
+	V   string `json:"v,omitempty"`
+	Err error  `json:"-"`
+}
+
+// CreateOptions collects the optional parameters of the Create method.
+type CreateOptions struct {
+	// TTL is how long the entry lives for. Zero means it never expires.
+	TTL time.Duration
+	// Owner optionally identifies who created the entry.
+	Owner string
+	// Key requests a custom key instead of a generated one.
+	Key string
+}
+
+// CreateOption sets an optional parameter of the Create method.
+type CreateOption func(*CreateOptions)
+
+// WithTTL makes the created entry expire after d.
+func WithTTL(d time.Duration) CreateOption {
+	return func(o *CreateOptions) { o.TTL = d }
+}
+
+// WithOwner records id as the owner of the created entry.
+func WithOwner(id string) CreateOption {
+	return func(o *CreateOptions) { o.Owner = id }
+}
+
+// WithKey requests k as the key of the created entry.
+func WithKey(k string) CreateOption {
+	return func(o *CreateOptions) { o.Key = k }
+}
+
+// NewCreateOptions applies opts to a zero CreateOptions.
+func NewCreateOptions(opts ...CreateOption) CreateOptions {
+	var o CreateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ServiceOption configures a Service.
+type ServiceOption func(*service)
+
+// WithKeyGenerator makes the service generate keys with g, and only accept
+// keys in its format. The default is an MD5 generator with DefaultKeyFormat.
+func WithKeyGenerator(g KeyGenerator) ServiceOption {
+	return func(s *service) { s.keys = g }
+}
+
+// WithBlocklist makes the service skip generated keys that contain a word of
+// b, counting them in skipped, and reject custom keys that do. A nil skipped
+// counts nothing.
+func WithBlocklist(b *Blocklist, skipped metrics.Counter) ServiceOption {
+	if skipped == nil {
+		skipped = discard.NewCounter()
+	}
+	return func(s *service) { s.blocklist, s.blocked = b, skipped }
+}
+
+// KeyMetrics instruments the search for a free key in Create.
+type KeyMetrics struct {
+	// Shifts observes how many keys were tried after the first one, for
+	// every created key.
+	Shifts metrics.Histogram
+	// Grows counts the times the search moved on to longer keys.
+	Grows metrics.Counter
+	// Lengths observes the length of created keys.
+	Lengths metrics.Histogram
+}
+
+// WithKeyMetrics makes the service instrument the search for a free key in
+// Create with m.
+func WithKeyMetrics(m KeyMetrics) ServiceOption {
+	return func(s *service) { s.keyMetrics = m }
+}
+
+// WithChangeCounters makes the service count updates and deletions in
+// updates and deletes.
+func WithChangeCounters(updates, deletes metrics.Counter) ServiceOption {
+	return func(s *service) { s.updates, s.deletes = updates, deletes }
+}
+
+// WithHitRatio makes the service keep the share of looked up keys that were
+// found in g.
+func WithHitRatio(g metrics.Gauge) ServiceOption {
+	return func(s *service) { s.hitRatio = g }
+}
+
+// WithAnalytics makes the service collect the analytics of lookups in c,
+// and serve them from Analytics.
+func WithAnalytics(c *Collector) ServiceOption {
+	return func(s *service) { s.analytics = c }
+}
+
+// WithCanonicalizer makes the service store values in the canonical form of
+// c, keeping the value as given in the Original metadata when it differs.
+// Values are looked up by Find in their canonical form too.
+func WithCanonicalizer(c *Canonicalizer) ServiceOption {
+	return func(s *service) { s.canonical = c }
+}
+
+// WithLogging configures the logging middleware of the service.
+func WithLogging(opts ...LoggingOption) ServiceOption {
+	return func(s *service) { s.logging = opts }
 }
 
 // NewInMemService returns a memory backed Service with all of the expected middlewares wired in.
-func NewInMemService(logger log.Logger, inserts, lookups metrics.Counter) Service {
+func NewInMemService(logger log.Logger, inserts, lookups metrics.Counter, opts ...ServiceOption) Service {
+	return NewService(NewInMemStore(), logger, inserts, lookups, opts...)
+}
+
+// NewService returns a Service backed by the given Store with all of the
+// expected middlewares wired in.
+func NewService(store Store, logger log.Logger, inserts, lookups metrics.Counter, opts ...ServiceOption) Service {
+	s := &service{
+		store: store,
+		keys:  NewMD5Generator(DefaultKeyFormat),
+		keyMetrics: KeyMetrics{
+			Shifts:  discard.NewHistogram(),
+			Grows:   discard.NewCounter(),
+			Lengths: discard.NewHistogram(),
+		},
+		updates:  discard.NewCounter(),
+		deletes:  discard.NewCounter(),
+		hitRatio: discard.NewGauge(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
 	var svc Service
 	{
-		svc = &inMemService{m: map[string]string{}}
-		svc = LoggingMiddleware(logger)(svc)
-		svc = InstrumentingMiddleware(inserts, lookups)(svc)
+		svc = s
+		if s.analytics != nil {
+			svc = AnalyticsMiddleware(s.analytics, s.keys.Format().Alphabet)(svc)
+		}
+		svc = LoggingMiddleware(logger, s.logging...)(svc)
+		svc = InstrumentingMiddleware(inserts, lookups, s.updates, s.deletes, s.hitRatio)(svc)
 	}
 	return svc
 }
@@ -35,77 +175,406 @@ var (
 	ErrMaxSizeExceeded = errors.New("result exceeds maximum size")
 	// ErrKeyNotFound represents a missing key.
 	ErrKeyNotFound = errors.New("key not found")
+	// ErrKeyExpired represents a key whose TTL has passed.
+	ErrKeyExpired = errors.New("key expired")
+	// ErrInvalidTTL protects the Create method from negative TTLs.
+	ErrInvalidTTL = errors.New("invalid ttl")
+	// ErrInvalidKey represents a key with characters outside the key
+	// alphabet, or a custom key that is too short, too long, blocked or
+	// reserved.
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrKeyTaken represents a custom key that already maps to a different value.
+	ErrKeyTaken = errors.New("key taken")
 )
 
-type inMemService struct {
-	m map[string]string
-	sync.RWMutex
+type service struct {
+	store      Store
+	keys       KeyGenerator
+	keyMetrics KeyMetrics
+	blocklist  *Blocklist
+	blocked    metrics.Counter
+	updates    metrics.Counter
+	deletes    metrics.Counter
+	hitRatio   metrics.Gauge
+	analytics  *Collector
+	canonical  *Canonicalizer
+	logging    []LoggingOption
 }
 
 const (
-	maxLen     = 2083
-	minKeySize = 6
+	maxLen = 2083
+
+	minCustomKeySize = 4
+	maxCustomKeySize = 64
+
+	maxBatchSize = 10000
 )
 
 // Create implements Service.
-func (s *inMemService) Create(_ context.Context, v string) (string, error) {
-	if len(v) > maxLen {
-		return "", ErrMaxSizeExceeded
+func (s *service) Create(_ context.Context, v string, opts ...CreateOption) (string, error) {
+	o := NewCreateOptions(opts...)
+	if o.TTL < 0 {
+		return "", ErrInvalidTTL
 	}
+	return s.create(s.store, v, o, time.Now())
+}
 
-	var hasher hash.Hash
-	{
-		hasher = md5.New()
-		if _, err := hasher.Write([]byte(v)); err != nil {
-			return "", fmt.Errorf("failed to write hash: %v", err)
+// CreateBatch implements Service. The options apply to every value, except
+// for custom keys which can't be shared and are rejected. The whole batch
+// takes the store lock once.
+func (s *service) CreateBatch(_ context.Context, vs []string, opts ...CreateOption) ([]Result, error) {
+	if len(vs) > maxBatchSize {
+		return nil, ErrMaxSizeExceeded
+	}
+
+	o := NewCreateOptions(opts...)
+	if o.TTL < 0 {
+		return nil, ErrInvalidTTL
+	}
+	if o.Key != "" {
+		return nil, ErrInvalidKey
+	}
+
+	now := time.Now()
+	results := make([]Result, len(vs))
+	err := s.store.Batch(func(tx Store) error {
+		for i, v := range vs {
+			k, err := s.create(tx, v, o, now)
+			results[i] = Result{K: k, V: v, Err: err}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return results, nil
+}
 
-	vHash := base64.RawURLEncoding.EncodeToString(hasher.Sum(nil))
+// create stores v under a generated key, or the custom key in o.
+func (s *service) create(store Store, v string, o CreateOptions, now time.Time) (string, error) {
+	v, original, err := s.canonicalize(v)
+	if err != nil {
+		return "", err
+	}
 
-	s.Lock()
-	defer s.Unlock()
+	e := Entry{V: v, Metadata: Metadata{Owner: o.Owner, CreatedAt: now, Original: original}}
+	if o.TTL > 0 {
+		e.ExpiresAt = now.Add(o.TTL)
+	}
 
-	size := minKeySize
-	offset := 0
-	for {
-		// If we've scanned the encoded hash and found no available slot
-		// we increase the key size and start scanning again.
-		// Worst hypothetical case is a 22 character key - the full MD5 hash in base64.
-		if offset+size > len(vHash) {
-			size++
-			offset = 0
-		}
-		k := vHash[offset : offset+size]
+	if o.Key != "" {
+		return s.createCustom(store, o.Key, e, now)
+	}
 
-		oldv, exists := s.m[k]
-		if exists {
-			if oldv == v { // same value
+	if h, ok := s.keys.(KeyHistory); ok {
+		for _, g := range h.Previous() {
+			k, found, err := s.existingKey(store, g, v, now)
+			if err != nil {
+				return "", err
+			}
+			if found {
 				return k, nil
 			}
-			offset++ // move key window
+		}
+	}
+
+	var prev string
+	for attempt := 0; ; attempt++ {
+		k, err := s.keys.Key(v, attempt)
+		if err != nil {
+			return "", err
+		}
+		if attempt > 0 && len(k) > len(prev) {
+			s.keyMetrics.Grows.Add(1)
+		}
+		prev = k
+		if reservedKeys[k] {
+			continue
+		}
+		if s.isBlocked(k) {
+			s.blocked.Add(1)
+			continue
+		}
+
+		old, stored, err := store.PutIfAbsent(k, e)
+		if err != nil {
+			return "", err
+		}
+		// An expired entry keeps its slot until it is reaped, but is never
+		// handed out again.
+		if stored || (old.V == v && !old.Expired(now)) { // found slot or same value
+			if stored {
+				s.forget(k)
+			}
+			s.keyMetrics.Shifts.Observe(float64(attempt))
+			s.keyMetrics.Lengths.Observe(float64(len(k)))
+			return k, nil
+		}
+	}
+}
+
+// existingKey looks for a live entry holding v among the keys g proposes for
+// it. Like Create, the search stops at the first free key that isn't blocked.
+func (s *service) existingKey(store Store, g KeyGenerator, v string, now time.Time) (string, bool, error) {
+	for attempt := 0; ; attempt++ {
+		k, err := g.Key(v, attempt)
+		if err == errKeySpaceExhausted {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+
+		e, err := store.Get(k)
+		if err == ErrKeyNotFound && (reservedKeys[k] || s.isBlocked(k)) {
 			continue
 		}
+		if err == ErrKeyNotFound {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if e.V == v && !e.Expired(now) {
+			return k, true, nil
+		}
+	}
+}
 
-		// found slot
-		s.m[k] = v
-		return k, nil
+// createCustom stores e under the caller's chosen key. Requesting a key that
+// already holds the same value is not an error, and an expired entry that
+// hasn't been reaped yet is replaced.
+func (s *service) createCustom(store Store, k string, e Entry, now time.Time) (string, error) {
+	if len(k) < minCustomKeySize || len(k) > maxCustomKeySize {
+		return "", ErrInvalidKey
+	}
+	k, err := s.key(k)
+	if err != nil {
+		return "", err
+	}
+	if reservedKeys[k] || s.isBlocked(k) {
+		return "", ErrInvalidKey
 	}
 
+	created := false
+	err = store.Batch(func(tx Store) error {
+		old, stored, err := tx.PutIfAbsent(k, e)
+		switch {
+		case err != nil:
+			return err
+		case stored:
+			created = true
+			return nil
+		case old.V == e.V && !old.Expired(now):
+			return nil
+		case old.Expired(now):
+			created = true
+			return tx.Update(k, func(old *Entry) error {
+				*old = e
+				return nil
+			})
+		}
+		return ErrKeyTaken
+	})
+	if err != nil {
+		return "", err
+	}
+	if created {
+		s.forget(k)
+	}
+	return k, nil
 }
 
-// Lookup implements Service.
-func (s *inMemService) Lookup(_ context.Context, k string) (string, error) {
+// forget drops the analytics of a key handed out anew, which may be left
+// from an entry that was reaped or replaced after expiring.
+func (s *service) forget(k string) {
+	if s.analytics != nil {
+		s.analytics.forget(k)
+	}
+}
+
+// key normalizes k to the alphabet of generated keys, or returns
+// ErrInvalidKey if it doesn't belong to it.
+func (s *service) key(k string) (string, error) {
 	if len(k) > maxLen {
 		return "", ErrMaxSizeExceeded
 	}
 
-	s.Lock()
-	defer s.Unlock()
+	alphabet := s.keys.Format().Alphabet
+	k = alphabet.Normalize(k)
+	if !alphabet.Valid(k) {
+		return "", ErrInvalidKey
+	}
+	return k, nil
+}
+
+// canonicalize returns the canonical form of v, and v itself if it differs,
+// or ErrMaxSizeExceeded if either is too long.
+func (s *service) canonicalize(v string) (string, string, error) {
+	if len(v) > maxLen {
+		return "", "", ErrMaxSizeExceeded
+	}
+	if s.canonical == nil {
+		return v, "", nil
+	}
+	canonical := s.canonical.Canonicalize(v)
+	if len(canonical) > maxLen {
+		return "", "", ErrMaxSizeExceeded
+	}
+	if canonical == v {
+		return v, "", nil
+	}
+	return canonical, v, nil
+}
+
+// reservedKeys are the words that transports route on where keys would
+// otherwise go, such as GET /api/batch, so they are never handed out.
+var reservedKeys = map[string]bool{
+	"batch": true,
+}
+
+func (s *service) isBlocked(k string) bool {
+	return s.blocklist != nil && s.blocklist.Blocked(k)
+}
+
+// Lookup implements Service.
+func (s *service) Lookup(_ context.Context, k string) (string, error) {
+	return s.lookup(s.store, k, time.Now())
+}
+
+// LookupBatch implements Service. The whole batch takes the store lock once.
+func (s *service) LookupBatch(_ context.Context, ks []string) ([]Result, error) {
+	if len(ks) > maxBatchSize {
+		return nil, ErrMaxSizeExceeded
+	}
+
+	now := time.Now()
+	results := make([]Result, len(ks))
+	err := s.store.Batch(func(tx Store) error {
+		for i, k := range ks {
+			v, err := s.lookup(tx, k, now)
+			results[i] = Result{K: k, V: v, Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// lookup returns the value under k and records the access at time now.
+func (s *service) lookup(store Store, k string, now time.Time) (string, error) {
+	k, err := s.key(k)
+	if err != nil {
+		return "", err
+	}
+
+	e, err := store.Touch(k, now)
+	if err != nil {
+		return "", err
+	}
+	if e.Expired(now) {
+		return "", ErrKeyExpired
+	}
+	return e.V, nil
+}
+
+// Stat implements Service. Expired entries can be inspected until they
+// are reaped.
+func (s *service) Stat(_ context.Context, k string) (Metadata, error) {
+	k, err := s.key(k)
+	if err != nil {
+		return Metadata{}, err
+	}
 
-	v, ok := s.m[k]
-	if !ok {
+	e, err := s.store.Get(k)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return e.Metadata, nil
+}
+
+// Find implements Service. It returns the key of the oldest live entry
+// holding v, whether its key was generated or chosen, without recording an
+// access.
+func (s *service) Find(_ context.Context, v string) (string, error) {
+	v, _, err := s.canonicalize(v)
+	if err != nil {
+		return "", err
+	}
+
+	items, err := s.store.Find(v)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	var found *Item
+	for i := range items {
+		if items[i].Expired(now) {
+			continue
+		}
+		if found == nil || items[i].CreatedAt.Before(found.CreatedAt) {
+			found = &items[i]
+		}
+	}
+	if found == nil {
 		return "", ErrKeyNotFound
 	}
-	return v, nil
+	return found.K, nil
+}
+
+// Update implements Service. The entry keeps its metadata, including its
+// expiry, and only its value and original value change.
+func (s *service) Update(_ context.Context, k, v string) error {
+	v, original, err := s.canonicalize(v)
+	if err != nil {
+		return err
+	}
+	k, err = s.key(k)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.store.Update(k, func(e *Entry) error {
+		if e.Expired(now) {
+			return ErrKeyExpired
+		}
+		e.V, e.Original = v, original
+		return nil
+	})
+}
+
+// Delete implements Service.
+func (s *service) Delete(_ context.Context, k string) error {
+	k, err := s.key(k)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Delete(k)
+}
+
+// Analytics implements Service. A zero to means now, and a zero from the
+// beginning of the retention period.
+func (s *service) Analytics(_ context.Context, k string, from, to time.Time) (Analytics, error) {
+	if s.analytics == nil {
+		return Analytics{}, ErrAnalyticsDisabled
+	}
+	k, err := s.key(k)
+	if err != nil {
+		return Analytics{}, err
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if to.Before(from) {
+		return Analytics{}, ErrInvalidRange
+	}
+
+	if _, err := s.store.Get(k); err != nil {
+		return Analytics{}, err
+	}
+	return s.analytics.Analytics(k, from, to), nil
 }
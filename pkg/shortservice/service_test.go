@@ -0,0 +1,408 @@
+package shortservice
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+func TestCreateCustomKey(t *testing.T) {
+	svc := NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	ctx := context.Background()
+
+	for _, testcase := range []struct {
+		v, key, want string
+		err          error
+	}{
+		{"http://a.com", "spring-sale", "spring-sale", nil},
+		{"http://a.com", "spring-sale", "spring-sale", nil}, // same value
+		{"http://b.com", "spring-sale", "", ErrKeyTaken},
+		{"http://b.com", "abc", "", ErrInvalidKey},
+		{"http://b.com", "spring/sale", "", ErrInvalidKey},
+		{"http://b.com", "batch", "", ErrInvalidKey}, // routed to the batch endpoints
+		{"http://b.com", "Batch", "Batch", nil},
+	} {
+		k, err := svc.Create(ctx, testcase.v, WithKey(testcase.key))
+		if k != testcase.want || err != testcase.err {
+			t.Errorf("Create(%q, %q): want %q (%v), have %q (%v)", testcase.v, testcase.key, testcase.want, testcase.err, k, err)
+		}
+	}
+}
+
+func TestCreateCustomKeyExpired(t *testing.T) {
+	store := NewInMemStore()
+	svc := NewService(store, log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	ctx := context.Background()
+
+	// Expired entries hold their key until reaped, but don't keep it taken.
+	for _, v := range []string{"http://a.com", "http://b.com"} {
+		store.PutIfAbsent("spring-sale", Entry{V: "http://a.com", Metadata: Metadata{Owner: "alice", ExpiresAt: time.Now()}})
+		if k, err := svc.Create(ctx, v, WithKey("spring-sale")); k != "spring-sale" || err != nil {
+			t.Errorf("Create(%q) over an expired key: want spring-sale, have %q (%v)", v, k, err)
+		}
+		if have, err := svc.Lookup(ctx, "spring-sale"); have != v || err != nil {
+			t.Errorf("Lookup: want %q, have %q (%v)", v, have, err)
+		}
+		if m, err := svc.Stat(ctx, "spring-sale"); m.Owner != "" || !m.ExpiresAt.IsZero() || m.Lookups != 1 || err != nil {
+			t.Errorf("Stat: want the metadata of the new entry, have %+v (%v)", m, err)
+		}
+		svc.Delete(ctx, "spring-sale")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	store := NewInMemStore()
+	svc := NewService(store, log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	ctx := context.Background()
+
+	store.PutIfAbsent("gone", Entry{V: "http://a.com", Metadata: Metadata{ExpiresAt: time.Now()}})
+	if _, err := svc.Lookup(ctx, "gone"); err != ErrKeyExpired {
+		t.Errorf("Lookup: want %v, have %v", ErrKeyExpired, err)
+	}
+	if _, err := svc.Create(ctx, "http://a.com", WithTTL(-time.Second)); err != ErrInvalidTTL {
+		t.Errorf("Create: want %v, have %v", ErrInvalidTTL, err)
+	}
+
+	k, err := svc.Create(ctx, "http://a.com", WithTTL(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := svc.Stat(ctx, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := m.CreatedAt.Add(time.Hour), m.ExpiresAt; !want.Equal(have) {
+		t.Errorf("Stat: want expiry %v, have %v", want, have)
+	}
+}
+
+func TestStat(t *testing.T) {
+	svc := NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	ctx := context.Background()
+
+	before := time.Now()
+	k, err := svc.Create(ctx, "http://a.com", WithOwner("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := svc.Stat(ctx, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Owner != "alice" || m.CreatedAt.Before(before) || !m.AccessedAt.IsZero() || m.Lookups != 0 || !m.ExpiresAt.IsZero() {
+		t.Errorf("Stat after Create: want owner alice, created since %v and never looked up, have %+v", before, m)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Lookup(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err = svc.Stat(ctx, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Lookups != 2 || m.AccessedAt.Before(m.CreatedAt) {
+		t.Errorf("Stat after Lookup: want 2 lookups accessed after creation, have %+v", m)
+	}
+	if _, err := svc.Stat(ctx, "nope"); err != ErrKeyNotFound {
+		t.Errorf("Stat of a missing key: want %v, have %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestUpdateDelete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	open := func() (*FileStore, Service) {
+		store, err := NewFileStore(dir, 0, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store, NewService(store, log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	}
+
+	ctx := context.Background()
+	store, svc := open()
+	a, err := svc.Create(ctx, "http://a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := svc.Create(ctx, "http://b.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Update(ctx, a, "http://c.com"); err != nil {
+		t.Errorf("Update: %v", err)
+	}
+	if err := svc.Delete(ctx, b); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	store.PutIfAbsent("gone", Entry{V: "http://a.com", Metadata: Metadata{ExpiresAt: time.Now()}})
+	if err := svc.Update(ctx, "gone", "http://b.com"); err != ErrKeyExpired {
+		t.Errorf("Update of an expired key: want %v, have %v", ErrKeyExpired, err)
+	}
+	if err := svc.Update(ctx, "nope", "http://b.com"); err != ErrKeyNotFound {
+		t.Errorf("Update of a missing key: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if err := svc.Delete(ctx, "nope"); err != ErrKeyNotFound {
+		t.Errorf("Delete of a missing key: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates and deletes survive a replay of the log.
+	store, svc = open()
+	defer store.Close()
+	if v, err := svc.Lookup(ctx, a); v != "http://c.com" || err != nil {
+		t.Errorf("Lookup of an updated key: want http://c.com, have %q (%v)", v, err)
+	}
+	if _, err := svc.Lookup(ctx, b); err != ErrKeyNotFound {
+		t.Errorf("Lookup of a deleted key: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if e, err := store.Get("gone"); e.V != "http://a.com" || err != nil {
+		t.Errorf("Get of a key whose Update failed: want http://a.com, have %q (%v)", e.V, err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileStore, err := NewFileStore(dir, 0, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileStore.Close()
+
+	ctx := context.Background()
+	for name, store := range map[string]Store{"inmem": NewInMemStore(), "file": fileStore} {
+		svc := NewService(store, log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+		k, err := svc.Create(ctx, "http://a.com", WithTTL(50*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := svc.Lookup(ctx, k); v != "http://a.com" || err != nil {
+			t.Errorf("%s: Lookup before expiry: want http://a.com, have %q (%v)", name, v, err)
+		}
+		time.Sleep(60 * time.Millisecond)
+
+		// Expired keys are told apart from missing ones until reaped.
+		if _, err := svc.Lookup(ctx, k); err != ErrKeyExpired {
+			t.Errorf("%s: Lookup after expiry: want %v, have %v", name, ErrKeyExpired, err)
+		}
+		if _, err := svc.Lookup(ctx, "nope"); err != ErrKeyNotFound {
+			t.Errorf("%s: Lookup of a missing key: want %v, have %v", name, ErrKeyNotFound, err)
+		}
+		if n, err := store.Reap(time.Now()); n != 1 || err != nil {
+			t.Errorf("%s: Reap: want 1 removed, have %d (%v)", name, n, err)
+		}
+		if _, err := svc.Lookup(ctx, k); err != ErrKeyNotFound {
+			t.Errorf("%s: Lookup after Reap: want %v, have %v", name, ErrKeyNotFound, err)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	svc := NewInMemService(log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	ctx := context.Background()
+
+	k, err := svc.Create(ctx, "http://a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := svc.CreateBatch(ctx, []string{"http://a.com", "http://b.com", strings.Repeat("x", maxLen+1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created[0].K != k || created[0].Err != nil {
+		t.Errorf("CreateBatch: want existing key %q, have %q (%v)", k, created[0].K, created[0].Err)
+	}
+	if created[2].Err != ErrMaxSizeExceeded {
+		t.Errorf("CreateBatch: want %v, have %v", ErrMaxSizeExceeded, created[2].Err)
+	}
+	if _, err := svc.CreateBatch(ctx, []string{"http://a.com"}, WithKey("spring-sale")); err != ErrInvalidKey {
+		t.Errorf("CreateBatch: want %v, have %v", ErrInvalidKey, err)
+	}
+
+	found, err := svc.LookupBatch(ctx, []string{created[1].K, "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "http://b.com", found[0].V; want != have || found[0].Err != nil {
+		t.Errorf("LookupBatch: want %q, have %q (%v)", want, have, found[0].Err)
+	}
+	if found[1].Err != ErrKeyNotFound {
+		t.Errorf("LookupBatch: want %v, have %v", ErrKeyNotFound, found[1].Err)
+	}
+}
+
+func TestKeyMetrics(t *testing.T) {
+	var (
+		shifts  = generic.NewHistogram("shifts", 10)
+		grows   = generic.NewCounter("grows")
+		lengths = generic.NewHistogram("lengths", 10)
+		store   = NewInMemStore()
+	)
+	svc := NewService(store, log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(),
+		WithKeyMetrics(KeyMetrics{Shifts: shifts, Grows: grows, Lengths: lengths}))
+	ctx := context.Background()
+
+	// Take every window of 6 of 12345, so the next create has to move on
+	// to a key of 7.
+	for attempt := 0; attempt < 17; attempt++ {
+		k, _ := NewMD5Generator(DefaultKeyFormat).Key("12345", attempt)
+		store.PutIfAbsent(k, Entry{V: "taken"})
+	}
+	if k, err := svc.Create(ctx, "12345"); k != "gnzLDuq" || err != nil {
+		t.Fatalf("Create: want gnzLDuq, have %q (%v)", k, err)
+	}
+	if want, have := 17.0, shifts.Quantile(0.5); want != have {
+		t.Errorf("shifts: want %v, have %v", want, have)
+	}
+	if want, have := 1.0, grows.Value(); want != have {
+		t.Errorf("grows: want %v, have %v", want, have)
+	}
+	if want, have := 7.0, lengths.Quantile(0.5); want != have {
+		t.Errorf("lengths: want %v, have %v", want, have)
+	}
+}
+
+func TestList(t *testing.T) {
+	store := NewInMemStore()
+	svc := NewService(store, log.NewNopLogger(), discard.NewCounter(), discard.NewCounter())
+	ctx := context.Background()
+
+	day := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, k := range []string{"e", "b", "d", "a", "c"} {
+		owner := "alice"
+		if k == "d" {
+			owner = "bob"
+		}
+		store.PutIfAbsent(k, Entry{V: "http://" + k + ".com", Metadata: Metadata{Owner: owner, CreatedAt: day.Add(time.Duration(i) * time.Hour)}})
+	}
+
+	keys := func(page Page) string {
+		var ks []string
+		for _, item := range page.Items {
+			ks = append(ks, item.K)
+		}
+		return strings.Join(ks, ",")
+	}
+
+	var have []string
+	var cursor string
+	for pages := 0; ; pages++ {
+		page, err := svc.List(ctx, cursor, 2, Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		have = append(have, keys(page))
+		if cursor = page.Cursor; cursor == "" {
+			break
+		}
+	}
+	if want := "a,b|c,d|e"; want != strings.Join(have, "|") {
+		t.Errorf("List: want pages %q, have %q", want, strings.Join(have, "|"))
+	}
+
+	for _, testcase := range []struct {
+		filter Filter
+		want   string
+	}{
+		{Filter{Owner: "alice"}, "a,b,c,e"},
+		{Filter{Prefix: "http://d"}, "d"},
+		{Filter{From: day.Add(time.Hour), To: day.Add(3 * time.Hour)}, "b,d"},
+		{Filter{Owner: "alice", From: day.Add(2 * time.Hour)}, "a,c"},
+	} {
+		page, err := svc.List(ctx, "", 0, testcase.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := keys(page); testcase.want != have || page.Cursor != "" {
+			t.Errorf("List(%+v): want %q, have %q (cursor %q)", testcase.filter, testcase.want, have, page.Cursor)
+		}
+	}
+
+	// A full page doesn't mean there are more.
+	if page, err := svc.List(ctx, "", 1, Filter{Owner: "bob"}); err != nil || page.Cursor != "" {
+		t.Errorf("List of the last entry: want no cursor, have %q (%v)", page.Cursor, err)
+	}
+
+	for _, testcase := range []struct {
+		cursor string
+		limit  int
+		filter Filter
+		err    error
+	}{
+		{"", -1, Filter{}, ErrInvalidLimit},
+		{"", MaxListLimit + 1, Filter{}, ErrInvalidLimit},
+		{"not a cursor!", 0, Filter{}, ErrInvalidCursor},
+		{"", 0, Filter{From: day, To: day.Add(-time.Hour)}, ErrInvalidRange},
+	} {
+		if _, err := svc.List(ctx, testcase.cursor, testcase.limit, testcase.filter); err != testcase.err {
+			t.Errorf("List(%q, %d, %+v): want %v, have %v", testcase.cursor, testcase.limit, testcase.filter, testcase.err, err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	store := NewInMemStore()
+	svc := NewService(store, log.NewNopLogger(), discard.NewCounter(), discard.NewCounter(), WithKeyGenerator(NewRandomGenerator(DefaultKeyFormat)))
+	ctx := context.Background()
+
+	if _, err := svc.Find(ctx, "http://a.com"); err != ErrKeyNotFound {
+		t.Errorf("Find before Create: want %v, have %v", ErrKeyNotFound, err)
+	}
+	// Random keys don't reuse the key of a value, so the oldest one is found.
+	first, err := svc.Create(ctx, "http://a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, "http://a.com", WithKey("spring-sale")); err != nil {
+		t.Fatal(err)
+	}
+	if k, err := svc.Find(ctx, "http://a.com"); k != first || err != nil {
+		t.Errorf("Find: want %q, have %q (%v)", first, k, err)
+	}
+
+	if err := svc.Update(ctx, first, "http://b.com"); err != nil {
+		t.Fatal(err)
+	}
+	if k, err := svc.Find(ctx, "http://a.com"); k != "spring-sale" || err != nil {
+		t.Errorf("Find after Update: want %q, have %q (%v)", "spring-sale", k, err)
+	}
+	if k, err := svc.Find(ctx, "http://b.com"); k != first || err != nil {
+		t.Errorf("Find of the updated value: want %q, have %q (%v)", first, k, err)
+	}
+
+	if err := svc.Delete(ctx, "spring-sale"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Find(ctx, "http://a.com"); err != ErrKeyNotFound {
+		t.Errorf("Find after Delete: want %v, have %v", ErrKeyNotFound, err)
+	}
+
+	store.PutIfAbsent("gone", Entry{V: "http://c.com", Metadata: Metadata{ExpiresAt: time.Now()}})
+	if _, err := svc.Find(ctx, "http://c.com"); err != ErrKeyNotFound {
+		t.Errorf("Find of an expired entry: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if n, _ := store.Reap(time.Now()); n != 1 {
+		t.Fatalf("Reap: want 1 removed, have %d", n)
+	}
+	if items, _ := store.Find("http://c.com"); len(items) != 0 {
+		t.Errorf("Find after Reap: want no entries, have %v", items)
+	}
+}
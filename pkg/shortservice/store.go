@@ -0,0 +1,339 @@
+package shortservice
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Entry is a value stored under a key.
+type Entry struct {
+	V string `json:"v"`
+	Metadata
+}
+
+// Metadata describes the lifecycle and usage of an entry.
+type Metadata struct {
+	// Owner optionally identifies who created the entry.
+	Owner     string    `json:"owner,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is the time after which the entry is no longer served.
+	// The zero value means the entry never expires.
+	ExpiresAt time.Time `json:"expires_at"`
+	// AccessedAt is the time of the last successful lookup.
+	AccessedAt time.Time `json:"accessed_at"`
+	// Lookups counts successful lookups.
+	Lookups uint64 `json:"lookups"`
+	// Original is the value as given, when it was canonicalized into a
+	// different one.
+	Original string `json:"original,omitempty"`
+}
+
+// Expired reports whether the entry has expired at time t.
+func (m Metadata) Expired(t time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !t.Before(m.ExpiresAt)
+}
+
+// touch records an access at time t, unless the entry has expired by then.
+func (e *Entry) touch(t time.Time) {
+	if e.Expired(t) {
+		return
+	}
+	e.AccessedAt = t
+	e.Lookups++
+}
+
+// Store describes a key/value storage backend for a Service.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the entry stored under k, or ErrKeyNotFound.
+	Get(k string) (Entry, error)
+	// PutIfAbsent stores e under k unless k is already taken. It returns
+	// the entry held under k after the call and whether e was stored.
+	PutIfAbsent(k string, e Entry) (actual Entry, stored bool, err error)
+	// Touch records an access at time t to the entry under k, unless it
+	// has expired, and returns the updated entry or ErrKeyNotFound.
+	Touch(k string, t time.Time) (Entry, error)
+	// Update atomically applies fn to the entry under k and stores the
+	// result. It returns ErrKeyNotFound or any error returned by fn, in
+	// which case the entry is left unchanged.
+	Update(k string, fn func(e *Entry) error) error
+	// Delete removes the entry under k, or returns ErrKeyNotFound.
+	Delete(k string) error
+	// Reap removes all entries that have expired at time t and returns
+	// how many were removed.
+	Reap(t time.Time) (int, error)
+	// Len returns the number of stored keys.
+	Len() (int, error)
+	// Range calls fn for the entries with keys greater than after, in key
+	// order, until fn returns false. The store may not be used by fn.
+	Range(after string, fn func(k string, e Entry) bool) error
+	// Find returns the entries holding the value v, expired or not, with
+	// their keys in key order.
+	Find(v string) ([]Item, error)
+	// Batch calls fn with a view of the store that holds its lock for the
+	// duration of the call, so a batch of operations takes the lock once.
+	// The view must not be used after fn returns.
+	Batch(fn func(tx Store) error) error
+}
+
+// Reap periodically removes expired entries from the store so their keys
+// can be reused. It blocks until ctx is canceled.
+func Reap(ctx context.Context, store Store, every time.Duration, logger log.Logger) error {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case t := <-ticker.C:
+			n, err := store.Reap(t)
+			if err != nil {
+				logger.Log("during", "reap", "err", err)
+				continue
+			}
+			if n > 0 {
+				logger.Log("during", "reap", "removed", n)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// NewInMemStore returns a Store backed by a simple in memory map.
+func NewInMemStore() Store {
+	return &inMemStore{m: newInMemMap()}
+}
+
+type inMemStore struct {
+	m inMemMap
+	sync.RWMutex
+}
+
+// Get implements Store.
+func (s *inMemStore) Get(k string) (Entry, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.m.Get(k)
+}
+
+// PutIfAbsent implements Store.
+func (s *inMemStore) PutIfAbsent(k string, e Entry) (Entry, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.m.PutIfAbsent(k, e)
+}
+
+// Touch implements Store.
+func (s *inMemStore) Touch(k string, t time.Time) (Entry, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.m.Touch(k, t)
+}
+
+// Update implements Store.
+func (s *inMemStore) Update(k string, fn func(e *Entry) error) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.m.Update(k, fn)
+}
+
+// Delete implements Store.
+func (s *inMemStore) Delete(k string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.m.Delete(k)
+}
+
+// Reap implements Store.
+func (s *inMemStore) Reap(t time.Time) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.m.Reap(t)
+}
+
+// Len implements Store.
+func (s *inMemStore) Len() (int, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.m.Len()
+}
+
+// Range implements Store. Readers hold the lock for the whole range.
+func (s *inMemStore) Range(after string, fn func(k string, e Entry) bool) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.m.Range(after, fn)
+}
+
+// Find implements Store.
+func (s *inMemStore) Find(v string) ([]Item, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.m.Find(v)
+}
+
+// Batch implements Store.
+func (s *inMemStore) Batch(fn func(tx Store) error) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return fn(s.m)
+}
+
+// inMemMap is the unsynchronised state behind an inMemStore: the entries by
+// key, the keys in order so Range doesn't sort them, and the keys holding
+// every value so Find doesn't scan them all. It implements Store for use
+// within a batch.
+type inMemMap struct {
+	entries map[string]Entry
+	order   *keyIndex
+	keys    map[string][]string // by value, rarely more than one
+}
+
+func newInMemMap() inMemMap {
+	return inMemMap{entries: map[string]Entry{}, order: &keyIndex{}, keys: map[string][]string{}}
+}
+
+// put stores e under k, keeping the indexes up to date.
+func (m inMemMap) put(k string, e Entry) {
+	old, ok := m.entries[k]
+	if ok && old.V == e.V {
+		m.entries[k] = e
+		return
+	}
+	if ok {
+		m.unindex(k, old.V)
+	} else {
+		m.order.insert(k)
+	}
+	m.entries[k] = e
+	m.keys[e.V] = append(m.keys[e.V], k)
+}
+
+// remove deletes the entry under k, if any, and its indexes.
+func (m inMemMap) remove(k string) {
+	e, ok := m.entries[k]
+	if !ok {
+		return
+	}
+	delete(m.entries, k)
+	m.order.remove(k)
+	m.unindex(k, e.V)
+}
+
+// unindex removes k from the keys holding v.
+func (m inMemMap) unindex(k, v string) {
+	ks := m.keys[v]
+	for i := range ks {
+		if ks[i] == k {
+			ks = append(ks[:i:i], ks[i+1:]...)
+			break
+		}
+	}
+	if len(ks) == 0 {
+		delete(m.keys, v)
+		return
+	}
+	m.keys[v] = ks
+}
+
+// Get implements Store.
+func (m inMemMap) Get(k string) (Entry, error) {
+	e, ok := m.entries[k]
+	if !ok {
+		return Entry{}, ErrKeyNotFound
+	}
+	return e, nil
+}
+
+// PutIfAbsent implements Store.
+func (m inMemMap) PutIfAbsent(k string, e Entry) (Entry, bool, error) {
+	if old, exists := m.entries[k]; exists {
+		return old, false, nil
+	}
+	m.put(k, e)
+	return e, true, nil
+}
+
+// Touch implements Store.
+func (m inMemMap) Touch(k string, t time.Time) (Entry, error) {
+	e, ok := m.entries[k]
+	if !ok {
+		return Entry{}, ErrKeyNotFound
+	}
+	e.touch(t)
+	m.entries[k] = e
+	return e, nil
+}
+
+// Update implements Store.
+func (m inMemMap) Update(k string, fn func(e *Entry) error) error {
+	e, ok := m.entries[k]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	if err := fn(&e); err != nil {
+		return err
+	}
+	m.put(k, e)
+	return nil
+}
+
+// Delete implements Store.
+func (m inMemMap) Delete(k string) error {
+	if _, ok := m.entries[k]; !ok {
+		return ErrKeyNotFound
+	}
+	m.remove(k)
+	return nil
+}
+
+// Reap implements Store.
+func (m inMemMap) Reap(t time.Time) (int, error) {
+	var n int
+	for k, e := range m.entries {
+		if e.Expired(t) {
+			m.remove(k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Len implements Store.
+func (m inMemMap) Len() (int, error) {
+	return len(m.entries), nil
+}
+
+// Range implements Store.
+func (m inMemMap) Range(after string, fn func(k string, e Entry) bool) error {
+	m.order.ascend(after, func(k string) bool { return fn(k, m.entries[k]) })
+	return nil
+}
+
+// Find implements Store.
+func (m inMemMap) Find(v string) ([]Item, error) {
+	items := make([]Item, 0, len(m.keys[v]))
+	for _, k := range m.keys[v] {
+		items = append(items, Item{K: k, Entry: m.entries[k]})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].K < items[j].K })
+	return items, nil
+}
+
+// Batch implements Store. The map is already held by the enclosing batch.
+func (m inMemMap) Batch(fn func(tx Store) error) error {
+	return fn(m)
+}
@@ -0,0 +1,125 @@
+package shortservice
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestInMemStore(t *testing.T) {
+	testStore(t, NewInMemStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewFileStore(dir, 0, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testStore(t, s)
+}
+
+// testStore checks that s, which must be empty, keeps the contract of Store.
+func testStore(t *testing.T, s Store) {
+	now := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := s.Get("a"); err != ErrKeyNotFound {
+		t.Errorf("Get of a missing key: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if e, stored, err := s.PutIfAbsent("a", Entry{V: "1"}); !stored || e.V != "1" || err != nil {
+		t.Errorf("PutIfAbsent: want 1 stored, have %q stored %v (%v)", e.V, stored, err)
+	}
+	if e, stored, err := s.PutIfAbsent("a", Entry{V: "2"}); stored || e.V != "1" || err != nil {
+		t.Errorf("PutIfAbsent of a taken key: want 1 not stored, have %q stored %v (%v)", e.V, stored, err)
+	}
+	if e, err := s.Get("a"); e.V != "1" || err != nil {
+		t.Errorf("Get: want 1, have %q (%v)", e.V, err)
+	}
+
+	// Touch
+	if e, err := s.Touch("a", now); e.Lookups != 1 || !e.AccessedAt.Equal(now) || err != nil {
+		t.Errorf("Touch: want 1 lookup at %v, have %d at %v (%v)", now, e.Lookups, e.AccessedAt, err)
+	}
+	if e, err := s.Get("a"); e.Lookups != 1 || err != nil {
+		t.Errorf("Get after Touch: want 1 lookup, have %d (%v)", e.Lookups, err)
+	}
+	if _, err := s.Touch("b", now); err != ErrKeyNotFound {
+		t.Errorf("Touch of a missing key: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if _, _, err := s.PutIfAbsent("x", Entry{V: "9", Metadata: Metadata{ExpiresAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	if e, err := s.Touch("x", now); e.Lookups != 0 || err != nil {
+		t.Errorf("Touch of an expired key: want no lookups, have %d (%v)", e.Lookups, err)
+	}
+
+	// Update
+	if err := s.Update("a", func(e *Entry) error { e.V = "3"; return nil }); err != nil {
+		t.Errorf("Update: %v", err)
+	}
+	if e, err := s.Get("a"); e.V != "3" || e.Lookups != 1 || err != nil {
+		t.Errorf("Get after Update: want 3 with 1 lookup, have %q with %d (%v)", e.V, e.Lookups, err)
+	}
+	errFailed := errors.New("failed")
+	if err := s.Update("a", func(e *Entry) error { e.V = "4"; return errFailed }); err != errFailed {
+		t.Errorf("Update failing: want %v, have %v", errFailed, err)
+	}
+	if e, err := s.Get("a"); e.V != "3" || err != nil {
+		t.Errorf("Get after a failed Update: want 3, have %q (%v)", e.V, err)
+	}
+	if err := s.Update("b", func(e *Entry) error { return nil }); err != ErrKeyNotFound {
+		t.Errorf("Update of a missing key: want %v, have %v", ErrKeyNotFound, err)
+	}
+
+	// Delete
+	if _, _, err := s.PutIfAbsent("b", Entry{V: "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("b"); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	if _, err := s.Get("b"); err != ErrKeyNotFound {
+		t.Errorf("Get after Delete: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if err := s.Delete("b"); err != ErrKeyNotFound {
+		t.Errorf("Delete of a missing key: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if _, stored, err := s.PutIfAbsent("b", Entry{V: "6"}); !stored || err != nil {
+		t.Errorf("PutIfAbsent of a deleted key: want stored, have %v (%v)", stored, err)
+	}
+
+	// Reap
+	if _, _, err := s.PutIfAbsent("y", Entry{V: "9", Metadata: Metadata{ExpiresAt: now.Add(time.Hour)}}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Reap(now); n != 1 || err != nil {
+		t.Errorf("Reap: want 1 removed, have %d (%v)", n, err)
+	}
+	if _, err := s.Get("x"); err != ErrKeyNotFound {
+		t.Errorf("Get of a reaped key: want %v, have %v", ErrKeyNotFound, err)
+	}
+	if n, err := s.Len(); n != 3 || err != nil {
+		t.Errorf("Len after Reap: want 3, have %d (%v)", n, err)
+	}
+	if n, err := s.Reap(now.Add(time.Hour)); n != 1 || err != nil {
+		t.Errorf("Reap later: want 1 removed, have %d (%v)", n, err)
+	}
+
+	var keys []string
+	if err := s.Range("", func(k string, _ Entry) bool { keys = append(keys, k); return true }); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Range: want [a b], have %v", keys)
+	}
+}
@@ -0,0 +1,124 @@
+package shorttransport
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/sgarcez/short/pb"
+	"github.com/sgarcez/short/pkg/shortservice"
+)
+
+// Callers looking keys up on behalf of visitors, such as a redirect service,
+// forward the Visit in the context of a lookup so analytics describe the
+// visitor rather than the caller. Over HTTP it travels in the VisitorHeader,
+// Referer and User-Agent headers, and over gRPC in the metadata keys below,
+// as gRPC sets the user-agent itself.
+const (
+	// VisitorHeader is the HTTP header, and gRPC metadata key, carrying
+	// the address of the visitor.
+	VisitorHeader = "X-Visitor"
+
+	grpcReferrer  = "referer"
+	grpcUserAgent = "x-user-agent"
+)
+
+// httpVisit is a transport/http.RequestFunc that puts the visitor, referrer
+// and user agent of the request in the context, for lookup analytics. The
+// visitor is the remote address unless forwarded in the VisitorHeader.
+func httpVisit(ctx context.Context, r *http.Request) context.Context {
+	visitor := r.Header.Get(VisitorHeader)
+	if visitor == "" {
+		visitor = remoteHost(r.RemoteAddr)
+	}
+	return shortservice.WithVisit(ctx, shortservice.Visit{
+		Visitor:   visitor,
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+	})
+}
+
+// httpRemoteVisit is a transport/http.RequestFunc like httpVisit that
+// ignores the VisitorHeader, for requests from the public.
+func httpRemoteVisit(ctx context.Context, r *http.Request) context.Context {
+	return shortservice.WithVisit(ctx, shortservice.Visit{
+		Visitor:   remoteHost(r.RemoteAddr),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+	})
+}
+
+// grpcVisit is a transport/grpc.ServerRequestFunc that puts the visitor,
+// referrer and user agent of the call in the context, for lookup analytics.
+// The visitor is the peer address and the user agent that of the gRPC
+// client, unless forwarded in the metadata.
+func grpcVisit(ctx context.Context, md metadata.MD) context.Context {
+	var v shortservice.Visit
+	if p, ok := peer.FromContext(ctx); ok {
+		v.Visitor = remoteHost(p.Addr.String())
+	}
+	if vals := md.Get(VisitorHeader); len(vals) > 0 && vals[0] != "" {
+		v.Visitor = vals[0]
+	}
+	if vals := md.Get(grpcReferrer); len(vals) > 0 {
+		v.Referrer = vals[0]
+	}
+	if vals := md.Get(grpcUserAgent); len(vals) > 0 {
+		v.UserAgent = vals[0]
+	} else if vals := md.Get("user-agent"); len(vals) > 0 {
+		v.UserAgent = vals[0]
+	}
+	return shortservice.WithVisit(ctx, v)
+}
+
+// httpForwardVisit is a transport/http.RequestFunc that forwards the Visit
+// in the context, if any, in the request headers.
+func httpForwardVisit(ctx context.Context, r *http.Request) context.Context {
+	v := shortservice.VisitFromContext(ctx)
+	for header, value := range map[string]string{VisitorHeader: v.Visitor, "Referer": v.Referrer, "User-Agent": v.UserAgent} {
+		if value != "" {
+			r.Header.Set(header, value)
+		}
+	}
+	return ctx
+}
+
+// grpcForwardVisit is a transport/grpc.ClientRequestFunc that forwards the
+// Visit in the context, if any, in the request metadata.
+func grpcForwardVisit(ctx context.Context, md *metadata.MD) context.Context {
+	v := shortservice.VisitFromContext(ctx)
+	for key, value := range map[string]string{VisitorHeader: v.Visitor, grpcReferrer: v.Referrer, grpcUserAgent: v.UserAgent} {
+		if value != "" {
+			md.Set(key, value)
+		}
+	}
+	return ctx
+}
+
+func buckets2pb(buckets []shortservice.Bucket) []*pb.Bucket {
+	out := make([]*pb.Bucket, len(buckets))
+	for i, b := range buckets {
+		out[i] = &pb.Bucket{Start: time2nanos(b.Start), Lookups: b.Lookups}
+	}
+	return out
+}
+
+func pb2buckets(buckets []*pb.Bucket) []shortservice.Bucket {
+	out := make([]shortservice.Bucket, len(buckets))
+	for i, b := range buckets {
+		out[i] = shortservice.Bucket{Start: time.Unix(0, b.Start).UTC(), Lookups: b.Lookups}
+	}
+	return out
+}
+
+// counts returns m, or an empty map if m is nil, as empty maps are sent as
+// nil.
+func counts(m map[string]uint64) map[string]uint64 {
+	if m == nil {
+		return map[string]uint64{}
+	}
+	return m
+}
@@ -0,0 +1,90 @@
+package shorttransport
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	httptransport "github.com/go-kit/kit/transport/http"
+
+	"github.com/sgarcez/short/pkg/shortendpoint"
+)
+
+// authorization is the header, and gRPC metadata key, carrying the bearer
+// token of the caller.
+const authorization = "Authorization"
+
+// ClientOption configures a client returned by NewHTTPClient or
+// NewGRPCClient.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	token string
+}
+
+// WithCredentials makes the client authenticate with the API key token.
+func WithCredentials(token string) ClientOption {
+	return func(o *clientOptions) { o.token = token }
+}
+
+func newClientOptions(opts ...ClientOption) clientOptions {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// http returns the HTTP client options that implement o. Visits in the
+// context are always forwarded.
+func (o clientOptions) http() []httptransport.ClientOption {
+	options := []httptransport.ClientOption{httptransport.ClientBefore(httpForwardVisit)}
+	if o.token != "" {
+		options = append(options, httptransport.ClientBefore(httptransport.SetRequestHeader(authorization, "Bearer "+o.token)))
+	}
+	return options
+}
+
+// grpc returns the gRPC client options that implement o. Visits in the
+// context are always forwarded.
+func (o clientOptions) grpc() []grpctransport.ClientOption {
+	options := []grpctransport.ClientOption{grpctransport.ClientBefore(grpcForwardVisit)}
+	if o.token != "" {
+		options = append(options, grpctransport.ClientBefore(grpctransport.SetRequestHeader(authorization, "Bearer "+o.token)))
+	}
+	return options
+}
+
+// AuthContext returns a copy of ctx that authenticates gRPC calls made with
+// it with the API key token, for calls made without a client such as
+// ImportGRPC.
+func AuthContext(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, authorization, "Bearer "+token)
+}
+
+// httpToken is a transport/http.RequestFunc that puts the bearer token of
+// the caller in the context.
+func httpToken(ctx context.Context, r *http.Request) context.Context {
+	return shortendpoint.WithToken(ctx, bearer(r.Header.Get(authorization)))
+}
+
+// grpcToken is a transport/grpc.ServerRequestFunc that puts the bearer token
+// of the caller in the context.
+func grpcToken(ctx context.Context, md metadata.MD) context.Context {
+	if vals := md.Get(authorization); len(vals) > 0 {
+		return shortendpoint.WithToken(ctx, bearer(vals[0]))
+	}
+	return ctx
+}
+
+// bearer returns the token of a bearer authorization header value.
+func bearer(header string) string {
+	const prefix = "Bearer "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
@@ -0,0 +1,123 @@
+package shorttransport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/sgarcez/short/pkg/shortendpoint"
+)
+
+// ClientIDHeader is the HTTP header, and gRPC metadata key, identifying the
+// caller for per-client rate limiting. It is only honored from trusted
+// proxies, as callers could otherwise pick a fresh identity on every
+// request, or another caller's. Other callers are identified by their
+// address, and authenticated callers by their API key.
+const ClientIDHeader = "X-Client-Id"
+
+// ServerOption configures a handler returned by NewHTTPHandler or
+// NewGRPCServer.
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	proxies []*net.IPNet
+}
+
+// WithTrustedProxies makes the server honor the ClientIDHeader of callers
+// whose address is in one of proxies.
+func WithTrustedProxies(proxies ...*net.IPNet) ServerOption {
+	return func(o *serverOptions) { o.proxies = proxies }
+}
+
+func newServerOptions(opts ...ServerOption) serverOptions {
+	var o serverOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ParseTrustedProxies parses a comma separated list of addresses and CIDR
+// ranges, such as "10.0.0.0/8,192.168.1.7".
+func ParseTrustedProxies(list string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address %q", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy range %q", s)
+		}
+		proxies = append(proxies, n)
+	}
+	return proxies, nil
+}
+
+// trusted reports whether host is the address of a trusted proxy.
+func (o serverOptions) trusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range o.proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpClientID is a transport/http.RequestFunc that puts the identity of the
+// caller in the context: the ClientIDHeader forwarded by a trusted proxy, or
+// else the remote address.
+func (o serverOptions) httpClientID(ctx context.Context, r *http.Request) context.Context {
+	host := remoteHost(r.RemoteAddr)
+	if id := r.Header.Get(ClientIDHeader); id != "" && o.trusted(host) {
+		return shortendpoint.WithClientID(ctx, id)
+	}
+	return shortendpoint.WithClientID(ctx, host)
+}
+
+// grpcClientID is a transport/grpc.ServerRequestFunc that puts the identity
+// of the caller in the context: the ClientIDHeader metadata forwarded by a
+// trusted proxy, or else the peer address.
+func (o serverOptions) grpcClientID(ctx context.Context, md metadata.MD) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	host := remoteHost(p.Addr.String())
+	if ids := md.Get(ClientIDHeader); len(ids) > 0 && ids[0] != "" && o.trusted(host) {
+		return shortendpoint.WithClientID(ctx, ids[0])
+	}
+	return shortendpoint.WithClientID(ctx, host)
+}
+
+// remoteHost strips the port from addr, so every connection from a host
+// shares an identity.
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
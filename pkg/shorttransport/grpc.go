@@ -2,10 +2,16 @@ package shorttransport
 
 import (
 	"context"
-	"errors"
+	"io"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/sony/gobreaker"
 	"golang.org/x/time/rate"
@@ -24,13 +30,43 @@ import (
 type grpcServer struct {
 	create grpctransport.Handler
 	lookup grpctransport.Handler
+	stat   grpctransport.Handler
+	update grpctransport.Handler
+	delete grpctransport.Handler
+
+	createBatch grpctransport.Handler
+	lookupBatch grpctransport.Handler
+
+	analytics grpctransport.Handler
+	find      grpctransport.Handler
+
+	// go-kit has no streaming transport, so streams call endpoints directly.
+	importBatch endpoint.Endpoint
+	listPage    endpoint.Endpoint
+	clientID    grpctransport.ServerRequestFunc
+	logger      log.Logger
 }
 
+const (
+	// importWindow is how many values of an import stream may be buffered
+	// before the server stops reading from it. gRPC flow control then
+	// blocks the client until the backlog is processed.
+	importWindow = 1000
+	// importBatchSize is the maximum number of buffered values created
+	// in a single batch.
+	importBatchSize = 100
+	// listPageSize is the number of entries sent per page of a list
+	// stream.
+	listPageSize = shortservice.DefaultListLimit
+)
+
 // NewGRPCServer makes a set of endpoints available as a gRPC ShortenServer.
-func NewGRPCServer(endpoints shortendpoint.Set, logger log.Logger) pb.ShortenServer {
+func NewGRPCServer(endpoints shortendpoint.Set, logger log.Logger, opts ...ServerOption) pb.ShortenServer {
+	o := newServerOptions(opts...)
 
 	options := []grpctransport.ServerOption{
 		grpctransport.ServerErrorLogger(logger),
+		grpctransport.ServerBefore(o.grpcClientID, grpcToken, grpcVisit),
 	}
 
 	return &grpcServer{
@@ -46,13 +82,59 @@ func NewGRPCServer(endpoints shortendpoint.Set, logger log.Logger) pb.ShortenSer
 			encodeGRPCLookupResponse,
 			options...,
 		),
+		stat: grpctransport.NewServer(
+			endpoints.StatEndpoint,
+			decodeGRPCStatRequest,
+			encodeGRPCStatResponse,
+			options...,
+		),
+		update: grpctransport.NewServer(
+			endpoints.UpdateEndpoint,
+			decodeGRPCUpdateRequest,
+			encodeGRPCUpdateResponse,
+			options...,
+		),
+		delete: grpctransport.NewServer(
+			endpoints.DeleteEndpoint,
+			decodeGRPCDeleteRequest,
+			encodeGRPCDeleteResponse,
+			options...,
+		),
+		createBatch: grpctransport.NewServer(
+			endpoints.CreateBatchEndpoint,
+			decodeGRPCCreateBatchRequest,
+			encodeGRPCCreateBatchResponse,
+			options...,
+		),
+		lookupBatch: grpctransport.NewServer(
+			endpoints.LookupBatchEndpoint,
+			decodeGRPCLookupBatchRequest,
+			encodeGRPCLookupBatchResponse,
+			options...,
+		),
+		analytics: grpctransport.NewServer(
+			endpoints.AnalyticsEndpoint,
+			decodeGRPCAnalyticsRequest,
+			encodeGRPCAnalyticsResponse,
+			options...,
+		),
+		find: grpctransport.NewServer(
+			endpoints.FindEndpoint,
+			decodeGRPCFindRequest,
+			encodeGRPCFindResponse,
+			options...,
+		),
+		importBatch: endpoints.ImportEndpoint,
+		listPage:    endpoints.ListStreamEndpoint,
+		clientID:    o.grpcClientID,
+		logger:      logger,
 	}
 }
 
 func (s *grpcServer) Create(ctx context.Context, req *pb.CreateRequest) (*pb.CreateReply, error) {
 	_, rep, err := s.create.ServeGRPC(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, err2status(err)
 	}
 	return rep.(*pb.CreateReply), nil
 }
@@ -60,17 +142,199 @@ func (s *grpcServer) Create(ctx context.Context, req *pb.CreateRequest) (*pb.Cre
 func (s *grpcServer) Lookup(ctx context.Context, req *pb.LookupRequest) (*pb.LookupReply, error) {
 	_, rep, err := s.lookup.ServeGRPC(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, err2status(err)
 	}
 	return rep.(*pb.LookupReply), nil
 }
 
+func (s *grpcServer) Stat(ctx context.Context, req *pb.StatRequest) (*pb.StatReply, error) {
+	_, rep, err := s.stat.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err2status(err)
+	}
+	return rep.(*pb.StatReply), nil
+}
+
+func (s *grpcServer) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateReply, error) {
+	_, rep, err := s.update.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err2status(err)
+	}
+	return rep.(*pb.UpdateReply), nil
+}
+
+func (s *grpcServer) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteReply, error) {
+	_, rep, err := s.delete.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err2status(err)
+	}
+	return rep.(*pb.DeleteReply), nil
+}
+
+func (s *grpcServer) CreateBatch(ctx context.Context, req *pb.CreateBatchRequest) (*pb.CreateBatchReply, error) {
+	_, rep, err := s.createBatch.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err2status(err)
+	}
+	return rep.(*pb.CreateBatchReply), nil
+}
+
+func (s *grpcServer) LookupBatch(ctx context.Context, req *pb.LookupBatchRequest) (*pb.LookupBatchReply, error) {
+	_, rep, err := s.lookupBatch.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err2status(err)
+	}
+	return rep.(*pb.LookupBatchReply), nil
+}
+
+func (s *grpcServer) Analytics(ctx context.Context, req *pb.AnalyticsRequest) (*pb.AnalyticsReply, error) {
+	_, rep, err := s.analytics.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err2status(err)
+	}
+	return rep.(*pb.AnalyticsReply), nil
+}
+
+func (s *grpcServer) Find(ctx context.Context, req *pb.FindRequest) (*pb.FindReply, error) {
+	_, rep, err := s.find.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err2status(err)
+	}
+	return rep.(*pb.FindReply), nil
+}
+
+// Import reads values from the stream into a bounded buffer, and creates
+// whatever has been buffered in batches while more values arrive. Results are
+// sent back in the order values were received.
+func (s *grpcServer) Import(stream pb.Shorten_ImportServer) error {
+	ctx := stream.Context()
+	// The stream isn't served by a transport/grpc.Server, so the request
+	// funcs are called here.
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = grpcToken(s.clientID(ctx, md), md)
+
+	reqs := make(chan *pb.ImportRequest, importWindow)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(reqs)
+		for {
+			req, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case reqs <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var next *pb.ImportRequest
+	for {
+		first := next
+		if first == nil {
+			var ok bool
+			if first, ok = <-reqs; !ok {
+				break
+			}
+		}
+		next = nil
+
+		// Batch up whatever is already buffered with the same options,
+		// without waiting for more values.
+		batch := shortendpoint.CreateBatchRequest{Vs: []string{first.V}, TTL: first.Ttl, Owner: first.Owner}
+	fill:
+		for len(batch.Vs) < importBatchSize {
+			select {
+			case req, ok := <-reqs:
+				if !ok {
+					break fill
+				}
+				if req.Ttl != batch.TTL || req.Owner != batch.Owner {
+					next = req
+					break fill
+				}
+				batch.Vs = append(batch.Vs, req.V)
+			default:
+				break fill
+			}
+		}
+
+		response, err := s.importBatch(ctx, batch)
+		if err != nil {
+			s.logger.Log("method", "Import", "err", err)
+			return err2status(err)
+		}
+		resp := response.(shortendpoint.CreateBatchResponse)
+		if resp.Err != nil {
+			return err2status(resp.Err)
+		}
+		for _, r := range results2pb(resp.Results) {
+			if err := stream.Send(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	default:
+		return nil
+	}
+}
+
+// List sends the entries selected by the request a page at a time, until the
+// limit is reached or there are no more. Every page carries the cursor that
+// resumes the listing after it, so an interrupted stream can be picked up.
+func (s *grpcServer) List(req *pb.ListRequest, stream pb.Shorten_ListServer) error {
+	ctx := stream.Context()
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = grpcToken(s.clientID(ctx, md), md)
+
+	if req.Limit < 0 {
+		return err2status(shortservice.ErrInvalidLimit)
+	}
+	filter := shortservice.Filter{Owner: req.Owner, Prefix: req.Prefix, From: nanos2time(req.From), To: nanos2time(req.To)}
+	cursor, remaining := req.Cursor, req.Limit
+	for {
+		limit := int64(listPageSize)
+		if req.Limit > 0 && remaining < limit {
+			limit = remaining
+		}
+		response, err := s.listPage(ctx, shortendpoint.ListRequest{Cursor: cursor, Limit: int(limit), Filter: filter})
+		if err != nil {
+			s.logger.Log("method", "List", "err", err)
+			return err2status(err)
+		}
+		resp := response.(shortendpoint.ListResponse)
+		if resp.Err != nil {
+			return err2status(resp.Err)
+		}
+		if err := stream.Send(&pb.ListReply{Items: items2pb(resp.Items), Cursor: resp.Cursor}); err != nil {
+			return err
+		}
+		remaining -= int64(len(resp.Items))
+		if resp.Cursor == "" || (req.Limit > 0 && remaining == 0) {
+			return nil
+		}
+		cursor = resp.Cursor
+	}
+}
+
 // NewGRPCClient returns a ShortService backed by a gRPC server at the other end
 // of the conn. The caller is responsible for constructing the conn, and
 // eventually closing the underlying transport. We bake-in certain middlewares,
 // implementing the client library pattern.
-func NewGRPCClient(conn *grpc.ClientConn, logger log.Logger) shortservice.Service {
+func NewGRPCClient(conn *grpc.ClientConn, logger log.Logger, opts ...ClientOption) shortservice.Service {
 
+	o := newClientOptions(opts...)
+	options := o.grpc()
 	limiter := ratelimit.NewErroringLimiter(rate.NewLimiter(50, 100))
 
 	// Each individual endpoint is an grpc/transport.Client (which implements
@@ -84,7 +348,11 @@ func NewGRPCClient(conn *grpc.ClientConn, logger log.Logger) shortservice.Servic
 			encodeGRPCCreateRequest,
 			decodeGRPCCreateResponse,
 			pb.CreateReply{},
+			options...,
 		).Endpoint()
+		createEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.CreateResponse{Err: err}
+		})(createEndpoint)
 		createEndpoint = limiter(createEndpoint)
 		createEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
 			Name:    "Create",
@@ -101,7 +369,11 @@ func NewGRPCClient(conn *grpc.ClientConn, logger log.Logger) shortservice.Servic
 			encodeGRPCLookupRequest,
 			decodeGRPCLookupResponse,
 			pb.LookupReply{},
+			options...,
 		).Endpoint()
+		lookupEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.LookupResponse{Err: err}
+		})(lookupEndpoint)
 		lookupEndpoint = limiter(lookupEndpoint)
 		lookupEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
 			Name:    "Lookup",
@@ -109,17 +381,291 @@ func NewGRPCClient(conn *grpc.ClientConn, logger log.Logger) shortservice.Servic
 		}))(lookupEndpoint)
 	}
 
+	var statEndpoint endpoint.Endpoint
+	{
+		statEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Shorten",
+			"Stat",
+			encodeGRPCStatRequest,
+			decodeGRPCStatResponse,
+			pb.StatReply{},
+			options...,
+		).Endpoint()
+		statEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.StatResponse{Err: err}
+		})(statEndpoint)
+		statEndpoint = limiter(statEndpoint)
+		statEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "Stat",
+			Timeout: 5 * time.Second,
+		}))(statEndpoint)
+	}
+
+	var updateEndpoint endpoint.Endpoint
+	{
+		updateEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Shorten",
+			"Update",
+			encodeGRPCUpdateRequest,
+			decodeGRPCUpdateResponse,
+			pb.UpdateReply{},
+			options...,
+		).Endpoint()
+		updateEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.UpdateResponse{Err: err}
+		})(updateEndpoint)
+		updateEndpoint = limiter(updateEndpoint)
+		updateEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "Update",
+			Timeout: 30 * time.Second,
+		}))(updateEndpoint)
+	}
+
+	var deleteEndpoint endpoint.Endpoint
+	{
+		deleteEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Shorten",
+			"Delete",
+			encodeGRPCDeleteRequest,
+			decodeGRPCDeleteResponse,
+			pb.DeleteReply{},
+			options...,
+		).Endpoint()
+		deleteEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.DeleteResponse{Err: err}
+		})(deleteEndpoint)
+		deleteEndpoint = limiter(deleteEndpoint)
+		deleteEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "Delete",
+			Timeout: 30 * time.Second,
+		}))(deleteEndpoint)
+	}
+
+	var createBatchEndpoint endpoint.Endpoint
+	{
+		createBatchEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Shorten",
+			"CreateBatch",
+			encodeGRPCCreateBatchRequest,
+			decodeGRPCCreateBatchResponse,
+			pb.CreateBatchReply{},
+			options...,
+		).Endpoint()
+		createBatchEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.CreateBatchResponse{Err: err}
+		})(createBatchEndpoint)
+		createBatchEndpoint = limiter(createBatchEndpoint)
+		createBatchEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "CreateBatch",
+			Timeout: 30 * time.Second,
+		}))(createBatchEndpoint)
+	}
+
+	var lookupBatchEndpoint endpoint.Endpoint
+	{
+		lookupBatchEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Shorten",
+			"LookupBatch",
+			encodeGRPCLookupBatchRequest,
+			decodeGRPCLookupBatchResponse,
+			pb.LookupBatchReply{},
+			options...,
+		).Endpoint()
+		lookupBatchEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.LookupBatchResponse{Err: err}
+		})(lookupBatchEndpoint)
+		lookupBatchEndpoint = limiter(lookupBatchEndpoint)
+		lookupBatchEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "LookupBatch",
+			Timeout: 5 * time.Second,
+		}))(lookupBatchEndpoint)
+	}
+
+	var analyticsEndpoint endpoint.Endpoint
+	{
+		analyticsEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Shorten",
+			"Analytics",
+			encodeGRPCAnalyticsRequest,
+			decodeGRPCAnalyticsResponse,
+			pb.AnalyticsReply{},
+			options...,
+		).Endpoint()
+		analyticsEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.AnalyticsResponse{Err: err}
+		})(analyticsEndpoint)
+		analyticsEndpoint = limiter(analyticsEndpoint)
+		analyticsEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "Analytics",
+			Timeout: 5 * time.Second,
+		}))(analyticsEndpoint)
+	}
+
+	var findEndpoint endpoint.Endpoint
+	{
+		findEndpoint = grpctransport.NewClient(
+			conn,
+			"pb.Shorten",
+			"Find",
+			encodeGRPCFindRequest,
+			decodeGRPCFindResponse,
+			pb.FindReply{},
+			options...,
+		).Endpoint()
+		findEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.FindResponse{Err: err}
+		})(findEndpoint)
+		findEndpoint = limiter(findEndpoint)
+		findEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "Find",
+			Timeout: 5 * time.Second,
+		}))(findEndpoint)
+	}
+
+	// List is a stream, so its endpoint reads the pages of a single call.
+	var listEndpoint endpoint.Endpoint
+	{
+		listEndpoint = makeGRPCListEndpoint(pb.NewShortenClient(conn), o.token)
+		listEndpoint = grpcErrorDecoder(func(err error) interface{} {
+			return shortendpoint.ListResponse{Err: err}
+		})(listEndpoint)
+		listEndpoint = limiter(listEndpoint)
+		listEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "List",
+			Timeout: 5 * time.Second,
+		}))(listEndpoint)
+	}
+
 	return shortendpoint.Set{
-		CreateEndpoint: createEndpoint,
-		LookupEndpoint: lookupEndpoint,
+		CreateEndpoint:      createEndpoint,
+		LookupEndpoint:      lookupEndpoint,
+		StatEndpoint:        statEndpoint,
+		UpdateEndpoint:      updateEndpoint,
+		DeleteEndpoint:      deleteEndpoint,
+		CreateBatchEndpoint: createBatchEndpoint,
+		LookupBatchEndpoint: lookupBatchEndpoint,
+		AnalyticsEndpoint:   analyticsEndpoint,
+		ListEndpoint:        listEndpoint,
+		FindEndpoint:        findEndpoint,
+	}
+}
+
+// makeGRPCListEndpoint returns a client endpoint that lists a page of
+// entries by reading every page of a List stream limited to the page size,
+// authenticated with the API key token if set.
+func makeGRPCListEndpoint(client pb.ShortenClient, token string) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(shortendpoint.ListRequest)
+		// The stream takes any limit, unlike List.
+		if req.Limit < 0 || req.Limit > shortservice.MaxListLimit {
+			return shortendpoint.ListResponse{Err: shortservice.ErrInvalidLimit}, nil
+		}
+		limit := req.Limit
+		if limit == 0 {
+			limit = shortservice.DefaultListLimit
+		}
+		if token != "" {
+			ctx = AuthContext(ctx, token)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream, err := client.List(ctx, &pb.ListRequest{
+			Cursor: req.Cursor,
+			Limit:  int64(limit),
+			Owner:  req.Filter.Owner,
+			Prefix: req.Filter.Prefix,
+			From:   time2nanos(req.Filter.From),
+			To:     time2nanos(req.Filter.To),
+		})
+		if err != nil {
+			return nil, err
+		}
+		page := shortservice.Page{Items: []shortservice.Item{}}
+		for {
+			reply, err := stream.Recv()
+			if err == io.EOF {
+				return shortendpoint.ListResponse{Page: page}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			page.Items = append(page.Items, pb2items(reply.Items)...)
+			page.Cursor = reply.Cursor
+		}
+	}
+}
+
+// ImportGRPC creates keys for every value received from values over a single
+// streaming Import call to the server at the other end of conn. It calls
+// handle with the result of each value, in order, as results arrive, and
+// returns once values is closed and every result has been handled. Sending
+// blocks while the server works through its backlog. Credentials are
+// attached to ctx with AuthContext.
+func ImportGRPC(ctx context.Context, conn *grpc.ClientConn, values <-chan string, handle func(shortservice.Result), opts ...shortservice.CreateOption) error {
+	o := shortservice.NewCreateOptions(opts...)
+	if o.Key != "" {
+		return shortservice.ErrInvalidKey
+	}
+	ttl := shortendpoint.Seconds(o.TTL)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := pb.NewShortenClient(conn).Import(ctx)
+	if err != nil {
+		return status2err(err)
 	}
+
+	sendc := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case v, ok := <-values:
+				if !ok {
+					sendc <- stream.CloseSend()
+					return
+				}
+				if err := stream.Send(&pb.ImportRequest{V: v, Ttl: ttl, Owner: o.Owner}); err != nil {
+					// io.EOF means the server ended the stream, and the
+					// reason is returned by Recv.
+					sendc <- err
+					return
+				}
+			case <-ctx.Done():
+				sendc <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return status2err(err)
+		}
+		handle(shortservice.Result{K: reply.K, V: reply.V, Err: str2err(reply.Err)})
+	}
+	if err := <-sendc; err != nil && err != io.EOF {
+		return err
+	}
+	return nil
 }
 
 // decodeGRPCCreateRequest is a transport/grpc.DecodeRequestFunc that converts a
 // gRPC Create request to a user-domain Create request. Primarily useful in a server.
 func decodeGRPCCreateRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
 	req := grpcReq.(*pb.CreateRequest)
-	return shortendpoint.CreateRequest{V: req.V}, nil
+	return shortendpoint.CreateRequest{V: req.V, TTL: req.Ttl, Owner: req.Owner, Key: req.Key}, nil
 }
 
 // decodeGRPCLookupRequest is a transport/grpc.DecodeRequestFunc that converts a
@@ -130,11 +676,35 @@ func decodeGRPCLookupRequest(_ context.Context, grpcReq interface{}) (interface{
 	return shortendpoint.LookupRequest{K: req.K}, nil
 }
 
+// decodeGRPCStatRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC stat request to a user-domain stat request. Primarily useful in a
+// server.
+func decodeGRPCStatRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.StatRequest)
+	return shortendpoint.StatRequest{K: req.K}, nil
+}
+
+// decodeGRPCUpdateRequest is a transport/grpc.DecodeRequestFunc that converts
+// a gRPC update request to a user-domain update request. Primarily useful in
+// a server.
+func decodeGRPCUpdateRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.UpdateRequest)
+	return shortendpoint.UpdateRequest{K: req.K, V: req.V}, nil
+}
+
+// decodeGRPCDeleteRequest is a transport/grpc.DecodeRequestFunc that converts
+// a gRPC delete request to a user-domain delete request. Primarily useful in
+// a server.
+func decodeGRPCDeleteRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.DeleteRequest)
+	return shortendpoint.DeleteRequest{K: req.K}, nil
+}
+
 // decodeGRPCCreateResponse is a transport/grpc.DecodeResponseFunc that converts a
 // gRPC Create reply to a user-domain Create response. Primarily useful in a client.
 func decodeGRPCCreateResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
 	reply := grpcReply.(*pb.CreateReply)
-	return shortendpoint.CreateResponse{K: reply.K, Err: str2err(reply.Err)}, nil
+	return shortendpoint.CreateResponse{K: reply.K}, nil
 }
 
 // decodeGRPCLookupResponse is a transport/grpc.DecodeResponseFunc that converts
@@ -142,14 +712,48 @@ func decodeGRPCCreateResponse(_ context.Context, grpcReply interface{}) (interfa
 // client.
 func decodeGRPCLookupResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
 	reply := grpcReply.(*pb.LookupReply)
-	return shortendpoint.LookupResponse{V: reply.V, Err: str2err(reply.Err)}, nil
+	return shortendpoint.LookupResponse{V: reply.V}, nil
+}
+
+// decodeGRPCStatResponse is a transport/grpc.DecodeResponseFunc that converts
+// a gRPC stat reply to a user-domain stat response. Primarily useful in a
+// client.
+func decodeGRPCStatResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.StatReply)
+	return shortendpoint.StatResponse{
+		Metadata: shortservice.Metadata{
+			Owner:      reply.Owner,
+			CreatedAt:  nanos2time(reply.CreatedAt),
+			ExpiresAt:  nanos2time(reply.ExpiresAt),
+			AccessedAt: nanos2time(reply.AccessedAt),
+			Lookups:    reply.Lookups,
+			Original:   reply.Original,
+		},
+	}, nil
+}
+
+// decodeGRPCUpdateResponse is a transport/grpc.DecodeResponseFunc that
+// converts a gRPC update reply to a user-domain update response. Primarily
+// useful in a client.
+func decodeGRPCUpdateResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	return shortendpoint.UpdateResponse{}, nil
+}
+
+// decodeGRPCDeleteResponse is a transport/grpc.DecodeResponseFunc that
+// converts a gRPC delete reply to a user-domain delete response. Primarily
+// useful in a client.
+func decodeGRPCDeleteResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	return shortendpoint.DeleteResponse{}, nil
 }
 
 // encodeGRPCCreateResponse is a transport/grpc.EncodeResponseFunc that converts a
 // user-domain Create response to a gRPC Create reply. Primarily useful in a server.
 func encodeGRPCCreateResponse(_ context.Context, response interface{}) (interface{}, error) {
 	resp := response.(shortendpoint.CreateResponse)
-	return &pb.CreateReply{K: resp.K, Err: err2str(resp.Err)}, nil
+	if resp.Err != nil {
+		return nil, err2status(resp.Err)
+	}
+	return &pb.CreateReply{K: resp.K}, nil
 }
 
 // encodeGRPCLookupResponse is a transport/grpc.EncodeResponseFunc that converts
@@ -157,14 +761,57 @@ func encodeGRPCCreateResponse(_ context.Context, response interface{}) (interfac
 // server.
 func encodeGRPCLookupResponse(_ context.Context, response interface{}) (interface{}, error) {
 	resp := response.(shortendpoint.LookupResponse)
-	return &pb.LookupReply{V: resp.V, Err: err2str(resp.Err)}, nil
+	if resp.Err != nil {
+		return nil, err2status(resp.Err)
+	}
+	return &pb.LookupReply{V: resp.V}, nil
+}
+
+// encodeGRPCStatResponse is a transport/grpc.EncodeResponseFunc that converts
+// a user-domain stat response to a gRPC stat reply. Primarily useful in a
+// server.
+func encodeGRPCStatResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(shortendpoint.StatResponse)
+	if resp.Err != nil {
+		return nil, err2status(resp.Err)
+	}
+	return &pb.StatReply{
+		Owner:      resp.Owner,
+		CreatedAt:  time2nanos(resp.CreatedAt),
+		ExpiresAt:  time2nanos(resp.ExpiresAt),
+		AccessedAt: time2nanos(resp.AccessedAt),
+		Lookups:    resp.Lookups,
+		Original:   resp.Original,
+	}, nil
+}
+
+// encodeGRPCUpdateResponse is a transport/grpc.EncodeResponseFunc that
+// converts a user-domain update response to a gRPC update reply. Primarily
+// useful in a server.
+func encodeGRPCUpdateResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(shortendpoint.UpdateResponse)
+	if resp.Err != nil {
+		return nil, err2status(resp.Err)
+	}
+	return &pb.UpdateReply{}, nil
+}
+
+// encodeGRPCDeleteResponse is a transport/grpc.EncodeResponseFunc that
+// converts a user-domain delete response to a gRPC delete reply. Primarily
+// useful in a server.
+func encodeGRPCDeleteResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(shortendpoint.DeleteResponse)
+	if resp.Err != nil {
+		return nil, err2status(resp.Err)
+	}
+	return &pb.DeleteReply{}, nil
 }
 
 // encodeGRPCCreateRequest is a transport/grpc.EncodeRequestFunc that converts a
 // user-domain Create request to a gRPC Create request. Primarily useful in a client.
 func encodeGRPCCreateRequest(_ context.Context, request interface{}) (interface{}, error) {
 	req := request.(shortendpoint.CreateRequest)
-	return &pb.CreateRequest{V: req.V}, nil
+	return &pb.CreateRequest{V: req.V, Ttl: req.TTL, Owner: req.Owner, Key: req.Key}, nil
 }
 
 // encodeGRPCLookupRequest is a transport/grpc.EncodeRequestFunc that converts a
@@ -175,21 +822,352 @@ func encodeGRPCLookupRequest(_ context.Context, request interface{}) (interface{
 	return &pb.LookupRequest{K: req.K}, nil
 }
 
-func str2err(s string) error {
-	if s == "" {
-		return nil
+// encodeGRPCStatRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain stat request to a gRPC stat request. Primarily useful in a
+// client.
+func encodeGRPCStatRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(shortendpoint.StatRequest)
+	return &pb.StatRequest{K: req.K}, nil
+}
+
+// encodeGRPCUpdateRequest is a transport/grpc.EncodeRequestFunc that converts
+// a user-domain update request to a gRPC update request. Primarily useful in
+// a client.
+func encodeGRPCUpdateRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(shortendpoint.UpdateRequest)
+	return &pb.UpdateRequest{K: req.K, V: req.V}, nil
+}
+
+// encodeGRPCDeleteRequest is a transport/grpc.EncodeRequestFunc that converts
+// a user-domain delete request to a gRPC delete request. Primarily useful in
+// a client.
+func encodeGRPCDeleteRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(shortendpoint.DeleteRequest)
+	return &pb.DeleteRequest{K: req.K}, nil
+}
+
+// decodeGRPCCreateBatchRequest is a transport/grpc.DecodeRequestFunc that
+// converts a gRPC batch create request to a user-domain batch create request.
+// Primarily useful in a server.
+func decodeGRPCCreateBatchRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.CreateBatchRequest)
+	return shortendpoint.CreateBatchRequest{Vs: req.Vs, TTL: req.Ttl, Owner: req.Owner}, nil
+}
+
+// decodeGRPCLookupBatchRequest is a transport/grpc.DecodeRequestFunc that
+// converts a gRPC batch lookup request to a user-domain batch lookup request.
+// Primarily useful in a server.
+func decodeGRPCLookupBatchRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.LookupBatchRequest)
+	return shortendpoint.LookupBatchRequest{Ks: req.Ks}, nil
+}
+
+// encodeGRPCCreateBatchResponse is a transport/grpc.EncodeResponseFunc that
+// converts a user-domain batch create response to a gRPC batch create reply.
+// Primarily useful in a server.
+func encodeGRPCCreateBatchResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(shortendpoint.CreateBatchResponse)
+	if resp.Err != nil {
+		return nil, err2status(resp.Err)
 	}
-	return errors.New(s)
+	return &pb.CreateBatchReply{Results: results2pb(resp.Results)}, nil
 }
 
-func err2str(err error) string {
-	if err == nil {
-		return ""
+// encodeGRPCLookupBatchResponse is a transport/grpc.EncodeResponseFunc that
+// converts a user-domain batch lookup response to a gRPC batch lookup reply.
+// Primarily useful in a server.
+func encodeGRPCLookupBatchResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(shortendpoint.LookupBatchResponse)
+	if resp.Err != nil {
+		return nil, err2status(resp.Err)
 	}
+	return &pb.LookupBatchReply{Results: results2pb(resp.Results)}, nil
+}
+
+// encodeGRPCCreateBatchRequest is a transport/grpc.EncodeRequestFunc that
+// converts a user-domain batch create request to a gRPC batch create request.
+// Primarily useful in a client.
+func encodeGRPCCreateBatchRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(shortendpoint.CreateBatchRequest)
+	return &pb.CreateBatchRequest{Vs: req.Vs, Ttl: req.TTL, Owner: req.Owner}, nil
+}
 
+// encodeGRPCLookupBatchRequest is a transport/grpc.EncodeRequestFunc that
+// converts a user-domain batch lookup request to a gRPC batch lookup request.
+// Primarily useful in a client.
+func encodeGRPCLookupBatchRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(shortendpoint.LookupBatchRequest)
+	return &pb.LookupBatchRequest{Ks: req.Ks}, nil
+}
+
+// decodeGRPCCreateBatchResponse is a transport/grpc.DecodeResponseFunc that
+// converts a gRPC batch create reply to a user-domain batch create response.
+// Primarily useful in a client.
+func decodeGRPCCreateBatchResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.CreateBatchReply)
+	return shortendpoint.CreateBatchResponse{Results: pb2results(reply.Results)}, nil
+}
+
+// decodeGRPCLookupBatchResponse is a transport/grpc.DecodeResponseFunc that
+// converts a gRPC batch lookup reply to a user-domain batch lookup response.
+// Primarily useful in a client.
+func decodeGRPCLookupBatchResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.LookupBatchReply)
+	return shortendpoint.LookupBatchResponse{Results: pb2results(reply.Results)}, nil
+}
+
+// decodeGRPCAnalyticsRequest is a transport/grpc.DecodeRequestFunc that
+// converts a gRPC analytics request to a user-domain analytics request.
+// Primarily useful in a server.
+func decodeGRPCAnalyticsRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.AnalyticsRequest)
+	return shortendpoint.AnalyticsRequest{K: req.K, From: nanos2time(req.From), To: nanos2time(req.To)}, nil
+}
+
+// encodeGRPCAnalyticsResponse is a transport/grpc.EncodeResponseFunc that
+// converts a user-domain analytics response to a gRPC analytics reply.
+// Primarily useful in a server.
+func encodeGRPCAnalyticsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(shortendpoint.AnalyticsResponse)
+	if resp.Err != nil {
+		return nil, err2status(resp.Err)
+	}
+	return &pb.AnalyticsReply{
+		Hourly:    buckets2pb(resp.Hourly),
+		Daily:     buckets2pb(resp.Daily),
+		Referrers: resp.Referrers,
+		Agents:    resp.Agents,
+		Visitors:  resp.Visitors,
+	}, nil
+}
+
+// encodeGRPCAnalyticsRequest is a transport/grpc.EncodeRequestFunc that
+// converts a user-domain analytics request to a gRPC analytics request.
+// Primarily useful in a client.
+func encodeGRPCAnalyticsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(shortendpoint.AnalyticsRequest)
+	return &pb.AnalyticsRequest{K: req.K, From: time2nanos(req.From), To: time2nanos(req.To)}, nil
+}
+
+// decodeGRPCAnalyticsResponse is a transport/grpc.DecodeResponseFunc that
+// converts a gRPC analytics reply to a user-domain analytics response.
+// Primarily useful in a client.
+func decodeGRPCAnalyticsResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.AnalyticsReply)
+	return shortendpoint.AnalyticsResponse{
+		Analytics: shortservice.Analytics{
+			Hourly:    pb2buckets(reply.Hourly),
+			Daily:     pb2buckets(reply.Daily),
+			Referrers: counts(reply.Referrers),
+			Agents:    counts(reply.Agents),
+			Visitors:  reply.Visitors,
+		},
+	}, nil
+}
+
+// decodeGRPCFindRequest is a transport/grpc.DecodeRequestFunc that converts a
+// gRPC find request to a user-domain find request. Primarily useful in a
+// server.
+func decodeGRPCFindRequest(_ context.Context, grpcReq interface{}) (interface{}, error) {
+	req := grpcReq.(*pb.FindRequest)
+	return shortendpoint.FindRequest{V: req.V}, nil
+}
+
+// encodeGRPCFindResponse is a transport/grpc.EncodeResponseFunc that converts
+// a user-domain find response to a gRPC find reply. Primarily useful in a
+// server.
+func encodeGRPCFindResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(shortendpoint.FindResponse)
+	if resp.Err != nil {
+		return nil, err2status(resp.Err)
+	}
+	return &pb.FindReply{K: resp.K}, nil
+}
+
+// encodeGRPCFindRequest is a transport/grpc.EncodeRequestFunc that converts a
+// user-domain find request to a gRPC find request. Primarily useful in a
+// client.
+func encodeGRPCFindRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(shortendpoint.FindRequest)
+	return &pb.FindRequest{V: req.V}, nil
+}
+
+// decodeGRPCFindResponse is a transport/grpc.DecodeResponseFunc that converts
+// a gRPC find reply to a user-domain find response. Primarily useful in a
+// client.
+func decodeGRPCFindResponse(_ context.Context, grpcReply interface{}) (interface{}, error) {
+	reply := grpcReply.(*pb.FindReply)
+	return shortendpoint.FindResponse{K: reply.K}, nil
+}
+
+// grpcErrors maps the errors a server may return to gRPC status codes.
+// Clients map a status back to its error by code and message.
+var grpcErrors = []struct {
+	err  error
+	code codes.Code
+}{
+	{shortservice.ErrKeyNotFound, codes.NotFound},
+	{shortservice.ErrKeyExpired, codes.NotFound},
+	{shortservice.ErrKeyTaken, codes.AlreadyExists},
+	{shortservice.ErrMaxSizeExceeded, codes.InvalidArgument},
+	{shortservice.ErrInvalidTTL, codes.InvalidArgument},
+	{shortservice.ErrInvalidKey, codes.InvalidArgument},
+	{shortservice.ErrInvalidRange, codes.InvalidArgument},
+	{shortservice.ErrInvalidCursor, codes.InvalidArgument},
+	{shortservice.ErrInvalidLimit, codes.InvalidArgument},
+	{shortservice.ErrAnalyticsDisabled, codes.Unimplemented},
+	{shortendpoint.ErrUnauthenticated, codes.Unauthenticated},
+	{shortendpoint.ErrForbidden, codes.PermissionDenied},
+	{ratelimit.ErrLimited, codes.ResourceExhausted},
+	{gobreaker.ErrOpenState, codes.Unavailable},
+	{gobreaker.ErrTooManyRequests, codes.Unavailable},
+}
+
+// err2status converts err to a gRPC status error with details describing
+// it. Unknown errors are reported as internal errors without leaking their
+// message.
+func err2status(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
 	switch err {
-	case shortservice.ErrKeyNotFound, shortservice.ErrMaxSizeExceeded:
-		return err.Error()
+	case context.Canceled:
+		return status.Error(codes.Canceled, err.Error())
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	var retryAfter time.Duration
+	if e, ok := err.(*shortendpoint.LimitedError); ok {
+		err, retryAfter = ratelimit.ErrLimited, e.RetryAfter
+	}
+
+	for _, e := range grpcErrors {
+		if e.err != err {
+			continue
+		}
+		st := status.New(e.code, err.Error())
+		var details []proto.Message
+		switch e.code {
+		case codes.NotFound, codes.AlreadyExists:
+			details = append(details, &errdetails.ResourceInfo{ResourceType: "key", Description: err.Error()})
+		case codes.InvalidArgument:
+			details = append(details, &errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Description: err.Error()},
+			}})
+		case codes.ResourceExhausted:
+			details = append(details, &errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+				{Subject: "requests", Description: err.Error()},
+			}})
+			if retryAfter > 0 {
+				details = append(details, &errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(retryAfter)})
+			}
+		}
+		if len(details) > 0 {
+			if withDetails, derr := st.WithDetails(details...); derr == nil {
+				st = withDetails
+			}
+		}
+		return st.Err()
+	}
+	return status.Error(codes.Internal, "Internal server error")
+}
+
+// status2err converts a gRPC status error back to the error the server
+// returned. Statuses that don't match a known error are returned as is.
+func status2err(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	for _, e := range grpcErrors {
+		if e.code == st.Code() && e.err.Error() == st.Message() {
+			return e.err
+		}
+	}
+	return err
+}
+
+// grpcErrorDecoder returns a client endpoint middleware that maps gRPC status
+// errors back to their original errors. Service errors are moved into the
+// response built by failed, like they are on the server, so they don't trip
+// the client side circuit breaker.
+func grpcErrorDecoder(failed func(error) interface{}) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, request)
+			if err == nil {
+				return response, nil
+			}
+			err = status2err(err)
+			if isServiceError(err) {
+				return failed(err), nil
+			}
+			return nil, err
+		}
+	}
+}
+
+func time2nanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func nanos2time(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func results2pb(results []shortservice.Result) []*pb.BatchResult {
+	batch := make([]*pb.BatchResult, len(results))
+	for i, r := range results {
+		batch[i] = &pb.BatchResult{K: r.K, V: r.V, Err: err2str(r.Err)}
+	}
+	return batch
+}
+
+func items2pb(items []shortservice.Item) []*pb.ListItem {
+	out := make([]*pb.ListItem, len(items))
+	for i, item := range items {
+		out[i] = &pb.ListItem{
+			K:          item.K,
+			V:          item.V,
+			Owner:      item.Owner,
+			CreatedAt:  time2nanos(item.CreatedAt),
+			ExpiresAt:  time2nanos(item.ExpiresAt),
+			AccessedAt: time2nanos(item.AccessedAt),
+			Lookups:    item.Lookups,
+			Original:   item.Original,
+		}
+	}
+	return out
+}
+
+func pb2items(items []*pb.ListItem) []shortservice.Item {
+	out := make([]shortservice.Item, len(items))
+	for i, item := range items {
+		out[i] = shortservice.Item{K: item.K, Entry: shortservice.Entry{
+			V: item.V,
+			Metadata: shortservice.Metadata{
+				Owner:      item.Owner,
+				CreatedAt:  nanos2time(item.CreatedAt),
+				ExpiresAt:  nanos2time(item.ExpiresAt),
+				AccessedAt: nanos2time(item.AccessedAt),
+				Lookups:    item.Lookups,
+				Original:   item.Original,
+			},
+		}}
+	}
+	return out
+}
+
+func pb2results(batch []*pb.BatchResult) []shortservice.Result {
+	results := make([]shortservice.Result, len(batch))
+	for i, r := range batch {
+		results[i] = shortservice.Result{K: r.K, V: r.V, Err: str2err(r.Err)}
 	}
-	return "Internal server error"
+	return results
 }
@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,11 +31,13 @@ import (
 
 // NewHTTPHandler returns an HTTP handler that makes a set of endpoints
 // available on predefined paths.
-func NewHTTPHandler(endpoints shortendpoint.Set, logger log.Logger) http.Handler {
+func NewHTTPHandler(endpoints shortendpoint.Set, logger log.Logger, opts ...ServerOption) http.Handler {
+	o := newServerOptions(opts...)
 
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorEncoder(errorEncoder),
 		httptransport.ServerErrorLogger(logger),
+		httptransport.ServerBefore(o.httpClientID, httpToken, httpVisit),
 	}
 
 	// m := http.NewServeMux()
@@ -44,12 +48,61 @@ func NewHTTPHandler(endpoints shortendpoint.Set, logger log.Logger) http.Handler
 		encodeHTTPGenericResponse,
 		options...,
 	))
+	r.Methods("POST").Path("/api/batch").Handler(httptransport.NewServer(
+		endpoints.CreateBatchEndpoint,
+		decodeHTTPCreateBatchRequest,
+		encodeHTTPBatchResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/api").Queries("v", "{v}").Handler(httptransport.NewServer(
+		endpoints.FindEndpoint,
+		decodeHTTPFindRequest,
+		encodeHTTPGenericResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/api").Handler(httptransport.NewServer(
+		endpoints.ListEndpoint,
+		decodeHTTPListRequest,
+		encodeHTTPGenericResponse,
+		options...,
+	))
+	// Registered ahead of the key routes, so "batch" is never taken as a key.
+	r.Methods("GET").Path("/api/batch").Handler(httptransport.NewServer(
+		endpoints.LookupBatchEndpoint,
+		decodeHTTPLookupBatchRequest,
+		encodeHTTPBatchResponse,
+		options...,
+	))
 	r.Methods("GET").Path("/api/{key}").Handler(httptransport.NewServer(
 		endpoints.LookupEndpoint,
 		decodeHTTPLookupRequest,
 		encodeHTTPGenericResponse,
 		options...,
 	))
+	r.Methods("GET").Path("/api/{key}/stat").Handler(httptransport.NewServer(
+		endpoints.StatEndpoint,
+		decodeHTTPStatRequest,
+		encodeHTTPGenericResponse,
+		options...,
+	))
+	r.Methods("GET").Path("/api/{key}/analytics").Handler(httptransport.NewServer(
+		endpoints.AnalyticsEndpoint,
+		decodeHTTPAnalyticsRequest,
+		encodeHTTPGenericResponse,
+		options...,
+	))
+	r.Methods("PUT").Path("/api/{key}").Handler(httptransport.NewServer(
+		endpoints.UpdateEndpoint,
+		decodeHTTPUpdateRequest,
+		encodeHTTPGenericResponse,
+		options...,
+	))
+	r.Methods("DELETE").Path("/api/{key}").Handler(httptransport.NewServer(
+		endpoints.DeleteEndpoint,
+		decodeHTTPDeleteRequest,
+		encodeHTTPGenericResponse,
+		options...,
+	))
 	return r
 }
 
@@ -57,7 +110,7 @@ func NewHTTPHandler(endpoints shortendpoint.Set, logger log.Logger) http.Handler
 // remote instance. We expect instance to come from a service discovery system,
 // so likely of the form "host:port". We bake-in certain middlewares,
 // implementing the client library pattern.
-func NewHTTPClient(instance string, logger log.Logger) (shortservice.Service, error) {
+func NewHTTPClient(instance string, logger log.Logger, opts ...ClientOption) (shortservice.Service, error) {
 	// Quickly sanitize the instance string.
 	if !strings.HasPrefix(instance, "http") {
 		instance = "http://" + instance
@@ -67,6 +120,7 @@ func NewHTTPClient(instance string, logger log.Logger) (shortservice.Service, er
 		return nil, err
 	}
 
+	options := newClientOptions(opts...).http()
 	limiter := ratelimit.NewErroringLimiter(rate.NewLimiter(50, 100))
 	breaker := circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
 		Timeout: 5 * time.Second,
@@ -83,6 +137,7 @@ func NewHTTPClient(instance string, logger log.Logger) (shortservice.Service, er
 			copyURL(u, "/api"),
 			encodeHTTPCreateRequest,
 			decodeHTTPCreateResponse,
+			options...,
 		).Endpoint()
 		createEndpoint = limiter(createEndpoint)
 		createEndpoint = breaker(createEndpoint)
@@ -97,16 +152,129 @@ func NewHTTPClient(instance string, logger log.Logger) (shortservice.Service, er
 			copyURL(u, "/api"),
 			encodeHTTPLookupRequest,
 			decodeHTTPLookupResponse,
+			options...,
 		).Endpoint()
 		lookupEndpoint = limiter(lookupEndpoint)
 		lookupEndpoint = breaker(lookupEndpoint)
 	}
 
+	var statEndpoint endpoint.Endpoint
+	{
+		statEndpoint = httptransport.NewClient(
+			"GET",
+			copyURL(u, "/api"),
+			encodeHTTPStatRequest,
+			decodeHTTPStatResponse,
+			options...,
+		).Endpoint()
+		statEndpoint = limiter(statEndpoint)
+		statEndpoint = breaker(statEndpoint)
+	}
+
+	var updateEndpoint endpoint.Endpoint
+	{
+		updateEndpoint = httptransport.NewClient(
+			"PUT",
+			copyURL(u, "/api"),
+			encodeHTTPUpdateRequest,
+			decodeHTTPUpdateResponse,
+			options...,
+		).Endpoint()
+		updateEndpoint = limiter(updateEndpoint)
+		updateEndpoint = breaker(updateEndpoint)
+	}
+
+	var deleteEndpoint endpoint.Endpoint
+	{
+		deleteEndpoint = httptransport.NewClient(
+			"DELETE",
+			copyURL(u, "/api"),
+			encodeHTTPDeleteRequest,
+			decodeHTTPDeleteResponse,
+			options...,
+		).Endpoint()
+		deleteEndpoint = limiter(deleteEndpoint)
+		deleteEndpoint = breaker(deleteEndpoint)
+	}
+
+	var createBatchEndpoint endpoint.Endpoint
+	{
+		createBatchEndpoint = httptransport.NewClient(
+			"POST",
+			copyURL(u, "/api/batch"),
+			encodeHTTPCreateRequest,
+			decodeHTTPCreateBatchResponse,
+			options...,
+		).Endpoint()
+		createBatchEndpoint = limiter(createBatchEndpoint)
+		createBatchEndpoint = breaker(createBatchEndpoint)
+	}
+
+	var lookupBatchEndpoint endpoint.Endpoint
+	{
+		lookupBatchEndpoint = httptransport.NewClient(
+			"GET",
+			copyURL(u, "/api/batch"),
+			encodeHTTPLookupBatchRequest,
+			decodeHTTPLookupBatchResponse,
+			options...,
+		).Endpoint()
+		lookupBatchEndpoint = limiter(lookupBatchEndpoint)
+		lookupBatchEndpoint = breaker(lookupBatchEndpoint)
+	}
+
+	var analyticsEndpoint endpoint.Endpoint
+	{
+		analyticsEndpoint = httptransport.NewClient(
+			"GET",
+			copyURL(u, "/api"),
+			encodeHTTPAnalyticsRequest,
+			decodeHTTPAnalyticsResponse,
+			options...,
+		).Endpoint()
+		analyticsEndpoint = limiter(analyticsEndpoint)
+		analyticsEndpoint = breaker(analyticsEndpoint)
+	}
+
+	var listEndpoint endpoint.Endpoint
+	{
+		listEndpoint = httptransport.NewClient(
+			"GET",
+			copyURL(u, "/api"),
+			encodeHTTPListRequest,
+			decodeHTTPListResponse,
+			options...,
+		).Endpoint()
+		listEndpoint = limiter(listEndpoint)
+		listEndpoint = breaker(listEndpoint)
+	}
+
+	var findEndpoint endpoint.Endpoint
+	{
+		findEndpoint = httptransport.NewClient(
+			"GET",
+			copyURL(u, "/api"),
+			encodeHTTPFindRequest,
+			decodeHTTPFindResponse,
+			options...,
+		).Endpoint()
+		findEndpoint = limiter(findEndpoint)
+		findEndpoint = breaker(findEndpoint)
+	}
+
 	// Returning the endpoint.Set as a service.Service relies on the
 	// endpoint.Set implementing the Service methods.
 	return shortendpoint.Set{
-		CreateEndpoint: createEndpoint,
-		LookupEndpoint: lookupEndpoint,
+		CreateEndpoint:      createEndpoint,
+		LookupEndpoint:      lookupEndpoint,
+		StatEndpoint:        statEndpoint,
+		UpdateEndpoint:      updateEndpoint,
+		DeleteEndpoint:      deleteEndpoint,
+		CreateBatchEndpoint: createBatchEndpoint,
+		LookupBatchEndpoint: lookupBatchEndpoint,
+		AnalyticsEndpoint:   analyticsEndpoint,
+		ListEndpoint:        listEndpoint,
+		FindEndpoint:        findEndpoint,
 	}, nil
 }
 
@@ -117,32 +285,146 @@ func copyURL(base *url.URL, path string) *url.URL {
 }
 
 func errorEncoder(_ context.Context, err error, w http.ResponseWriter) {
+	if e, ok := err.(*shortendpoint.LimitedError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
+	if err == shortendpoint.ErrUnauthenticated {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
 	w.WriteHeader(err2code(err))
 	json.NewEncoder(w).Encode(errorWrapper{Error: err.Error()})
 }
 
 func err2code(err error) int {
+	if _, ok := err.(*shortendpoint.LimitedError); ok {
+		return http.StatusTooManyRequests
+	}
 	switch err {
+	case shortendpoint.ErrUnauthenticated:
+		return http.StatusUnauthorized
+	case shortendpoint.ErrForbidden:
+		return http.StatusForbidden
+	case ratelimit.ErrLimited:
+		return http.StatusTooManyRequests
 	case shortservice.ErrKeyNotFound:
 		return http.StatusNotFound
-	case shortservice.ErrMaxSizeExceeded:
+	case shortservice.ErrKeyExpired:
+		return http.StatusGone
+	case shortservice.ErrKeyTaken:
+		return http.StatusConflict
+	case shortservice.ErrMaxSizeExceeded, shortservice.ErrInvalidTTL, shortservice.ErrInvalidKey, shortservice.ErrInvalidRange,
+		shortservice.ErrInvalidCursor, shortservice.ErrInvalidLimit:
 		return http.StatusBadRequest
+	case shortservice.ErrAnalyticsDisabled:
+		return http.StatusNotImplemented
 	}
 	return http.StatusInternalServerError
 }
 
+// httpErrors are the errors a client recognises in an error response body.
+var httpErrors = []error{
+	shortservice.ErrKeyNotFound,
+	shortservice.ErrKeyExpired,
+	shortservice.ErrKeyTaken,
+	shortservice.ErrMaxSizeExceeded,
+	shortservice.ErrInvalidTTL,
+	shortservice.ErrInvalidKey,
+	shortservice.ErrInvalidRange,
+	shortservice.ErrInvalidCursor,
+	shortservice.ErrInvalidLimit,
+	shortservice.ErrAnalyticsDisabled,
+	shortendpoint.ErrUnauthenticated,
+	shortendpoint.ErrForbidden,
+	ratelimit.ErrLimited,
+	gobreaker.ErrOpenState,
+	gobreaker.ErrTooManyRequests,
+}
+
+// errorDecoder decodes the error in a non-200 response body written by
+// errorEncoder, mapping it back to the original error where possible.
 func errorDecoder(r *http.Response) error {
 	var w errorWrapper
-	if err := json.NewDecoder(r.Body).Decode(&w); err != nil {
-		return err
+	if err := json.NewDecoder(r.Body).Decode(&w); err != nil || w.Error == "" {
+		return errors.New(r.Status)
+	}
+	return str2err(w.Error)
+}
+
+// str2err maps an error message back to the original error where possible.
+func str2err(s string) error {
+	if s == "" {
+		return nil
+	}
+	for _, err := range httpErrors {
+		if err.Error() == s {
+			return err
+		}
+	}
+	return errors.New(s)
+}
+
+func err2str(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// isServiceError reports whether err is returned by the service itself, or
+// rejects the credentials of the caller, as opposed to a transport or
+// middleware failure. Clients return service errors inside the response so
+// they don't trip the circuit breaker.
+func isServiceError(err error) bool {
+	switch err {
+	case shortservice.ErrKeyNotFound,
+		shortservice.ErrKeyExpired,
+		shortservice.ErrKeyTaken,
+		shortservice.ErrMaxSizeExceeded,
+		shortservice.ErrInvalidTTL,
+		shortservice.ErrInvalidKey,
+		shortservice.ErrInvalidRange,
+		shortservice.ErrInvalidCursor,
+		shortservice.ErrInvalidLimit,
+		shortservice.ErrAnalyticsDisabled,
+		shortendpoint.ErrUnauthenticated,
+		shortendpoint.ErrForbidden:
+		return true
 	}
-	return errors.New(w.Error)
+	return false
 }
 
 type errorWrapper struct {
 	Error string `json:"error"`
 }
 
+// batchResult is the JSON form of a shortservice.Result, carrying its error
+// as a message.
+type batchResult struct {
+	K     string `json:"k,omitempty"`
+	V     string `json:"v,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+func results2batch(results []shortservice.Result) []batchResult {
+	batch := make([]batchResult, len(results))
+	for i, r := range results {
+		batch[i] = batchResult{K: r.K, V: r.V, Error: err2str(r.Err)}
+	}
+	return batch
+}
+
+func batch2results(batch []batchResult) []shortservice.Result {
+	results := make([]shortservice.Result, len(batch))
+	for i, r := range batch {
+		results[i] = shortservice.Result{K: r.K, V: r.V, Err: str2err(r.Error)}
+	}
+	return results
+}
+
 // decodeHTTPCreateRequest is a transport/http.DecodeRequestFunc that decodes a
 // JSON-encoded create request from the HTTP request body. Primarily useful in a
 // server.
@@ -162,6 +444,48 @@ func decodeHTTPLookupRequest(_ context.Context, r *http.Request) (interface{}, e
 	return req, nil
 }
 
+// decodeHTTPStatRequest is a transport/http.DecodeRequestFunc that decodes a
+// stat request from the HTTP request path. Primarily useful in a server.
+func decodeHTTPStatRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return shortendpoint.StatRequest{K: vars["key"]}, nil
+}
+
+// decodeHTTPUpdateRequest is a transport/http.DecodeRequestFunc that decodes
+// a JSON-encoded update request from the HTTP request body, taking the key
+// from the request path. Primarily useful in a server.
+func decodeHTTPUpdateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req shortendpoint.UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+	req.K = mux.Vars(r)["key"]
+	return req, nil
+}
+
+// decodeHTTPDeleteRequest is a transport/http.DecodeRequestFunc that decodes
+// a delete request from the HTTP request path. Primarily useful in a server.
+func decodeHTTPDeleteRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	return shortendpoint.DeleteRequest{K: vars["key"]}, nil
+}
+
+// decodeHTTPCreateBatchRequest is a transport/http.DecodeRequestFunc that
+// decodes a JSON-encoded batch create request from the HTTP request body.
+// Primarily useful in a server.
+func decodeHTTPCreateBatchRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req shortendpoint.CreateBatchRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, err
+}
+
+// decodeHTTPLookupBatchRequest is a transport/http.DecodeRequestFunc that
+// decodes a batch lookup request from the repeated k query parameter.
+// Primarily useful in a server.
+func decodeHTTPLookupBatchRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return shortendpoint.LookupBatchRequest{Ks: r.URL.Query()["k"]}, nil
+}
+
 // decodeHTTPCreateResponse is a transport/http.DecodeResponseFunc that decodes a
 // JSON-encoded create response from the HTTP response body. If the response has a
 // non-200 status code, we will interpret that as an error and attempt to decode
@@ -169,7 +493,11 @@ func decodeHTTPLookupRequest(_ context.Context, r *http.Request) (interface{}, e
 // client.
 func decodeHTTPCreateResponse(_ context.Context, r *http.Response) (interface{}, error) {
 	if r.StatusCode != http.StatusOK {
-		return nil, errors.New(r.Status)
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.CreateResponse{Err: err}, nil
+		}
+		return nil, err
 	}
 	var resp shortendpoint.CreateResponse
 	err := json.NewDecoder(r.Body).Decode(&resp)
@@ -190,13 +518,281 @@ func encodeHTTPLookupRequest(ctx context.Context, r *http.Request, request inter
 // a client.
 func decodeHTTPLookupResponse(_ context.Context, r *http.Response) (interface{}, error) {
 	if r.StatusCode != http.StatusOK {
-		return nil, errors.New(r.Status)
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.LookupResponse{Err: err}, nil
+		}
+		return nil, err
 	}
 	var resp shortendpoint.LookupResponse
 	err := json.NewDecoder(r.Body).Decode(&resp)
 	return resp, err
 }
 
+// Primarily useful in a client.
+func encodeHTTPStatRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	sr, _ := request.(shortendpoint.StatRequest)
+	r.URL.Path = path.Join(r.URL.Path, sr.K, "stat")
+	return nil
+}
+
+// decodeHTTPStatResponse is a transport/http.DecodeResponseFunc that decodes
+// a JSON-encoded stat response from the HTTP response body. Non-200 responses
+// are decoded with errorDecoder. Primarily useful in a client.
+func decodeHTTPStatResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.StatResponse{Err: err}, nil
+		}
+		return nil, err
+	}
+	var resp shortendpoint.StatResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPAnalyticsRequest is a transport/http.DecodeRequestFunc that
+// decodes an analytics request from the HTTP request path, and the optional
+// RFC 3339 from and to query parameters. Primarily useful in a server.
+func decodeHTTPAnalyticsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := shortendpoint.AnalyticsRequest{K: mux.Vars(r)["key"]}
+	q := r.URL.Query()
+	for param, t := range map[string]*time.Time{"from": &req.From, "to": &req.To} {
+		if q.Get(param) == "" {
+			continue
+		}
+		var err error
+		if *t, err = time.Parse(time.RFC3339, q.Get(param)); err != nil {
+			return nil, shortservice.ErrInvalidRange
+		}
+	}
+	return req, nil
+}
+
+// encodeHTTPAnalyticsRequest is a transport/http.EncodeRequestFunc that puts
+// the key in the request path and the time range in the query. Primarily
+// useful in a client.
+func encodeHTTPAnalyticsRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	ar, _ := request.(shortendpoint.AnalyticsRequest)
+	r.URL.Path = path.Join(r.URL.Path, ar.K, "analytics")
+	q := url.Values{}
+	if !ar.From.IsZero() {
+		q.Set("from", ar.From.Format(time.RFC3339))
+	}
+	if !ar.To.IsZero() {
+		q.Set("to", ar.To.Format(time.RFC3339))
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// decodeHTTPAnalyticsResponse is a transport/http.DecodeResponseFunc that
+// decodes a JSON-encoded analytics response from the HTTP response body.
+// Non-200 responses are decoded with errorDecoder. Primarily useful in a
+// client.
+func decodeHTTPAnalyticsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.AnalyticsResponse{Err: err}, nil
+		}
+		return nil, err
+	}
+	var resp shortendpoint.AnalyticsResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPListRequest is a transport/http.DecodeRequestFunc that decodes a
+// list request from the cursor, limit, owner and prefix query parameters, and
+// the optional RFC 3339 from and to creation times. Primarily useful in a
+// server.
+func decodeHTTPListRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	req := shortendpoint.ListRequest{
+		Cursor: q.Get("cursor"),
+		Filter: shortservice.Filter{Owner: q.Get("owner"), Prefix: q.Get("prefix")},
+	}
+	if q.Get("limit") != "" {
+		var err error
+		if req.Limit, err = strconv.Atoi(q.Get("limit")); err != nil {
+			return nil, shortservice.ErrInvalidLimit
+		}
+	}
+	for param, t := range map[string]*time.Time{"from": &req.Filter.From, "to": &req.Filter.To} {
+		if q.Get(param) == "" {
+			continue
+		}
+		var err error
+		if *t, err = time.Parse(time.RFC3339, q.Get(param)); err != nil {
+			return nil, shortservice.ErrInvalidRange
+		}
+	}
+	return req, nil
+}
+
+// encodeHTTPListRequest is a transport/http.EncodeRequestFunc that puts the
+// cursor, limit and filter in the query. Primarily useful in a client.
+func encodeHTTPListRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	lr, _ := request.(shortendpoint.ListRequest)
+	q := url.Values{}
+	for param, value := range map[string]string{"cursor": lr.Cursor, "owner": lr.Filter.Owner, "prefix": lr.Filter.Prefix} {
+		if value != "" {
+			q.Set(param, value)
+		}
+	}
+	if lr.Limit != 0 {
+		q.Set("limit", strconv.Itoa(lr.Limit))
+	}
+	if !lr.Filter.From.IsZero() {
+		q.Set("from", lr.Filter.From.Format(time.RFC3339Nano))
+	}
+	if !lr.Filter.To.IsZero() {
+		q.Set("to", lr.Filter.To.Format(time.RFC3339Nano))
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// decodeHTTPListResponse is a transport/http.DecodeResponseFunc that decodes
+// a JSON-encoded list response from the HTTP response body. Non-200
+// responses are decoded with errorDecoder. Primarily useful in a client.
+func decodeHTTPListResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.ListResponse{Err: err}, nil
+		}
+		return nil, err
+	}
+	var resp shortendpoint.ListResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// decodeHTTPFindRequest is a transport/http.DecodeRequestFunc that decodes a
+// find request from the v query parameter. Primarily useful in a server.
+func decodeHTTPFindRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return shortendpoint.FindRequest{V: r.URL.Query().Get("v")}, nil
+}
+
+// Primarily useful in a client.
+func encodeHTTPFindRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	fr, _ := request.(shortendpoint.FindRequest)
+	r.URL.RawQuery = url.Values{"v": {fr.V}}.Encode()
+	return nil
+}
+
+// decodeHTTPFindResponse is a transport/http.DecodeResponseFunc that decodes
+// a JSON-encoded find response from the HTTP response body. Non-200
+// responses are decoded with errorDecoder. Primarily useful in a client.
+func decodeHTTPFindResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.FindResponse{Err: err}, nil
+		}
+		return nil, err
+	}
+	var resp shortendpoint.FindResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// encodeHTTPUpdateRequest is a transport/http.EncodeRequestFunc that puts the
+// key in the request path and JSON-encodes the new value to the request body.
+// Primarily useful in a client.
+func encodeHTTPUpdateRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	ur, _ := request.(shortendpoint.UpdateRequest)
+	r.URL.Path = path.Join(r.URL.Path, ur.K)
+	return encodeHTTPCreateRequest(ctx, r, ur)
+}
+
+// decodeHTTPUpdateResponse is a transport/http.DecodeResponseFunc that decodes
+// a JSON-encoded update response from the HTTP response body. Non-200
+// responses are decoded with errorDecoder. Primarily useful in a client.
+func decodeHTTPUpdateResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.UpdateResponse{Err: err}, nil
+		}
+		return nil, err
+	}
+	var resp shortendpoint.UpdateResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// Primarily useful in a client.
+func encodeHTTPDeleteRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	dr, _ := request.(shortendpoint.DeleteRequest)
+	r.URL.Path = path.Join(r.URL.Path, dr.K)
+	return nil
+}
+
+// decodeHTTPDeleteResponse is a transport/http.DecodeResponseFunc that decodes
+// a JSON-encoded delete response from the HTTP response body. Non-200
+// responses are decoded with errorDecoder. Primarily useful in a client.
+func decodeHTTPDeleteResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.DeleteResponse{Err: err}, nil
+		}
+		return nil, err
+	}
+	var resp shortendpoint.DeleteResponse
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+
+// Primarily useful in a client.
+func encodeHTTPLookupBatchRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	lr, _ := request.(shortendpoint.LookupBatchRequest)
+	r.URL.RawQuery = url.Values{"k": lr.Ks}.Encode()
+	return nil
+}
+
+// decodeHTTPCreateBatchResponse is a transport/http.DecodeResponseFunc that
+// decodes a JSON-encoded batch create response from the HTTP response body.
+// Non-200 responses are decoded with errorDecoder. Primarily useful in a
+// client.
+func decodeHTTPCreateBatchResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.CreateBatchResponse{Err: err}, nil
+		}
+		return nil, err
+	}
+	var resp batchResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return shortendpoint.CreateBatchResponse{Results: batch2results(resp.Results)}, nil
+}
+
+// decodeHTTPLookupBatchResponse is a transport/http.DecodeResponseFunc that
+// decodes a JSON-encoded batch lookup response from the HTTP response body.
+// Non-200 responses are decoded with errorDecoder. Primarily useful in a
+// client.
+func decodeHTTPLookupBatchResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	if r.StatusCode != http.StatusOK {
+		err := errorDecoder(r)
+		if isServiceError(err) {
+			return shortendpoint.LookupBatchResponse{Err: err}, nil
+		}
+		return nil, err
+	}
+	var resp batchResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return shortendpoint.LookupBatchResponse{Results: batch2results(resp.Results)}, nil
+}
+
 // encodeHTTPCreateRequest is a transport/http.EncodeRequestFunc that
 // JSON-encodes a Create request to the request body. Primarily useful in a client.
 func encodeHTTPCreateRequest(_ context.Context, r *http.Request, request interface{}) error {
@@ -218,3 +814,21 @@ func encodeHTTPGenericResponse(ctx context.Context, w http.ResponseWriter, respo
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	return json.NewEncoder(w).Encode(response)
 }
+
+// encodeHTTPBatchResponse is a transport/http.EncodeResponseFunc that encodes
+// a batch response as JSON, with the error of each item as a message.
+// Primarily useful in a server.
+func encodeHTTPBatchResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		errorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	var results []shortservice.Result
+	switch resp := response.(type) {
+	case shortendpoint.CreateBatchResponse:
+		results = resp.Results
+	case shortendpoint.LookupBatchResponse:
+		results = resp.Results
+	}
+	return encodeHTTPGenericResponse(ctx, w, batchResponse{Results: results2batch(results)})
+}
@@ -0,0 +1,143 @@
+package shorttransport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+
+	"github.com/sgarcez/short/pkg/shortendpoint"
+	"github.com/sgarcez/short/pkg/shortservice"
+)
+
+// errNotRedirectable is returned for values that aren't absolute HTTP URLs,
+// which browsers can't be sent to.
+var errNotRedirectable = errors.New("value is not a URL")
+
+// RedirectOption configures the handler returned by NewRedirectHandler.
+type RedirectOption func(*redirectOptions)
+
+type redirectOptions struct {
+	code   int
+	maxAge time.Duration
+}
+
+// WithRedirectCode makes the handler answer with code, one of 301, 302, 307
+// and 308. The default is 302.
+func WithRedirectCode(code int) RedirectOption {
+	return func(o *redirectOptions) { o.code = code }
+}
+
+// WithMaxAge lets browsers and proxies cache redirects for d, sparing the
+// service repeated visits at the cost of not seeing them as accesses, nor
+// updates and deletions until d has passed. The default is not to cache.
+func WithMaxAge(d time.Duration) RedirectOption {
+	return func(o *redirectOptions) { o.maxAge = d }
+}
+
+// NewRedirectHandler returns an HTTP handler that redirects GET and HEAD
+// requests for /{key} to the value under key, through the Lookup endpoint so
+// every redirect is recorded as an access. Missing and expired keys are
+// answered with a 404 or 410 page. It is meant to be served on its own
+// listener, to the public, so callers are only identified by their address.
+func NewRedirectHandler(endpoints shortendpoint.Set, logger log.Logger, opts ...RedirectOption) (http.Handler, error) {
+	o := redirectOptions{code: http.StatusFound}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	switch o.code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil, fmt.Errorf("invalid redirect code %d", o.code)
+	}
+	if o.maxAge < 0 {
+		return nil, fmt.Errorf("invalid max age %v", o.maxAge)
+	}
+
+	r := mux.NewRouter()
+	r.Methods("GET", "HEAD").Path("/{key}").Handler(httptransport.NewServer(
+		endpoints.LookupEndpoint,
+		decodeHTTPLookupRequest,
+		o.encodeRedirect,
+		httptransport.ServerErrorEncoder(redirectErrorEncoder),
+		httptransport.ServerErrorLogger(logger),
+		httptransport.ServerBefore(httpRemoteClientID, httpRemoteVisit),
+	))
+	return r, nil
+}
+
+// httpRemoteClientID is a transport/http.RequestFunc that puts the remote
+// address of the caller in the context as its identity. Public callers could
+// otherwise pick their own ClientIDHeader to dodge per-client limits.
+func httpRemoteClientID(ctx context.Context, r *http.Request) context.Context {
+	return shortendpoint.WithClientID(ctx, remoteHost(r.RemoteAddr))
+}
+
+// encodeRedirect is a transport/http.EncodeResponseFunc that redirects to the
+// value in a lookup response.
+func (o redirectOptions) encodeRedirect(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		redirectErrorEncoder(ctx, f.Failed(), w)
+		return nil
+	}
+	v := response.(shortendpoint.LookupResponse).V
+	u, err := url.Parse(v)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		redirectErrorEncoder(ctx, errNotRedirectable, w)
+		return nil
+	}
+
+	if o.maxAge > 0 {
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(o.maxAge/time.Second)))
+	} else {
+		// Browsers keep permanent redirects forever unless told otherwise.
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	w.Header().Set("Location", u.String())
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(o.code)
+	fmt.Fprintf(w, "<a href=\"%s\">%s</a>.\n", html.EscapeString(u.String()), http.StatusText(o.code))
+	return nil
+}
+
+// redirectErrorEncoder answers a failed redirect with an HTML page. Errors
+// are never cached, as a missing or expired key may be taken again.
+func redirectErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
+	if e, ok := err.(*shortendpoint.LimitedError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
+	code, msg := http.StatusInternalServerError, "Something went wrong, please try again later."
+	switch err {
+	case shortservice.ErrKeyNotFound, shortservice.ErrInvalidKey, shortservice.ErrMaxSizeExceeded, errNotRedirectable:
+		code, msg = http.StatusNotFound, "This short link doesn't exist."
+	case shortservice.ErrKeyExpired:
+		code, msg = http.StatusGone, "This short link has expired."
+	default:
+		if c := err2code(err); c == http.StatusTooManyRequests {
+			code, msg = c, "Too many requests, please try again later."
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, redirectErrorPage, code, http.StatusText(code), msg)
+}
+
+const redirectErrorPage = `<!DOCTYPE html>
+<html>
+<head><title>%[1]d %[2]s</title></head>
+<body><h1>%[2]s</h1><p>%[3]s</p></body>
+</html>
+`